@@ -0,0 +1,97 @@
+package cosmovisor
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/hashicorp/go-version"
+
+	upgradetypes "cosmossdk.io/x/upgrade/types"
+)
+
+// errAppVersionUnparseable is returned when the version command of the app succeeded but its
+// output is not the version info expected.
+var errAppVersionUnparseable = errors.New("unparseable app version")
+
+// queryAppVersion returns the version of the running app, as reported by its version command.
+func (fw *fileWatcher) queryAppVersion() (string, error) {
+	// cobra prints the version to stderr unless told otherwise
+	result, err := fw.execApp(true, "version", "--long", "--output", "json")
+	if err != nil {
+		return "", err
+	}
+
+	return parseAppVersion(result)
+}
+
+// parseAppVersion returns the version of the version info printed by the app. The whole output
+// must be that single JSON document, anything else being rejected rather than guessed at.
+func parseAppVersion(bz []byte) (string, error) {
+	var info struct {
+		Version string `json:"version"`
+	}
+	dec := json.NewDecoder(bytes.NewReader(bz))
+	if err := dec.Decode(&info); err != nil {
+		return "", fmt.Errorf("%w: %w", errAppVersionUnparseable, err)
+	}
+	if _, err := dec.Token(); !errors.Is(err, io.EOF) {
+		return "", fmt.Errorf("%w: unexpected output after the version info", errAppVersionUnparseable)
+	}
+	if info.Version == "" {
+		return "", fmt.Errorf("%w: app version is empty", errAppVersionUnparseable)
+	}
+
+	return info.Version, nil
+}
+
+// verifyAppVersion reports whether the version the upgrade plan points to is greater than the
+// version of the running app. A plan that would upgrade to the same or an older version is
+// rejected with a validation_failed callback. The check is skipped, logging why, when either
+// version cannot be determined, but a plan is rejected as well when the app reports a version
+// which cannot be parsed.
+func (fw *fileWatcher) verifyAppVersion(info upgradetypes.Plan, callback callbackInfo) bool {
+	if !fw.checkAppVersion {
+		return true
+	}
+
+	if callback.Version == "" {
+		fw.logger.Info("skipping app version check, the upgrade plan does not hold a version", "name", info.Name)
+		return true
+	}
+	planVersion, err := version.NewVersion(callback.Version)
+	if err != nil {
+		fw.logger.Error("skipping app version check, invalid upgrade plan version", "name", info.Name, "version", callback.Version, "error", err)
+		return true
+	}
+
+	var appVersion *version.Version
+	current, err := fw.getAppVersion()
+	if err == nil {
+		if appVersion, err = version.NewVersion(current); err != nil {
+			err = fmt.Errorf("%w %q: %w", errAppVersionUnparseable, current, err)
+		}
+	}
+	switch {
+	case errors.Is(err, errAppVersionUnparseable):
+		// the app answered, but with a version that cannot be compared: don't take the chance
+		fw.logger.Error("failed to parse the app version, ignoring the upgrade plan", "name", info.Name, "error", err)
+		callback.Error = err.Error()
+		_ = fw.callbacks.send(CallbackEventValidationFailed, callback)
+		return false
+	case err != nil:
+		fw.logger.Error("skipping app version check, failed to query the app version", "error", err)
+		return true
+	}
+
+	if planVersion.GreaterThan(appVersion) {
+		return true
+	}
+
+	fw.logger.Error("upgrade plan does not upgrade the app version, ignoring it", "name", info.Name, "version", callback.Version, "app_version", current)
+	callback.Error = fmt.Sprintf("upgrade version %s is not greater than the app version %s", callback.Version, current)
+	_ = fw.callbacks.send(CallbackEventValidationFailed, callback)
+	return false
+}
@@ -33,10 +33,100 @@ const (
 	EnvDisableLogs              = "COSMOVISOR_DISABLE_LOGS"
 	EnvColorLogs                = "COSMOVISOR_COLOR_LOGS"
 	EnvTimeFormatLogs           = "COSMOVISOR_TIMEFORMAT_LOGS"
+	EnvLogFormat                = "COSMOVISOR_LOG_FORMAT"
 	EnvCustomPreupgrade         = "COSMOVISOR_CUSTOM_PREUPGRADE"
 	EnvDisableRecase            = "COSMOVISOR_DISABLE_RECASE"
+	EnvCallbackAPI              = "CALLBACK_API"
+	EnvNodeID                   = "NODE_ID"
+	EnvDeploymentID             = "DEPLOYMENT_ID"
+	EnvCallbackEndpoints        = "COSMOVISOR_CALLBACK_ENDPOINTS"
+	EnvCallbackTags             = "COSMOVISOR_CALLBACK_TAGS"
+	EnvCallbackBreakerThreshold = "COSMOVISOR_CALLBACK_BREAKER_THRESHOLD"
+	EnvCallbackBreakerCooldown  = "COSMOVISOR_CALLBACK_BREAKER_COOLDOWN"
+	EnvMetricsAddr              = "COSMOVISOR_METRICS_ADDR"
+	EnvMinFileAge               = "COSMOVISOR_MIN_FILE_AGE"
+	EnvUpgradeInfoSchema        = "COSMOVISOR_UPGRADE_INFO_SCHEMA"
+	EnvLogThrottleInterval      = "COSMOVISOR_LOG_THROTTLE_INTERVAL"
+	EnvVerifyHeightReached      = "COSMOVISOR_VERIFY_HEIGHT_REACHED"
+	EnvHeightTolerance          = "COSMOVISOR_HEIGHT_TOLERANCE"
+	EnvPostHeightReachedHook    = "COSMOVISOR_POST_HEIGHT_REACHED_HOOK"
+	EnvPostHeightReachedTimeout = "COSMOVISOR_POST_HEIGHT_REACHED_HOOK_TIMEOUT"
+	EnvPostHeightReachedPolicy  = "COSMOVISOR_POST_HEIGHT_REACHED_HOOK_FAILURE_POLICY"
+	EnvPlatformPreference       = "COSMOVISOR_PLATFORM_PREFERENCE"
+	EnvRequireConfirmation      = "COSMOVISOR_REQUIRE_CONFIRMATION"
+	EnvConfirmationTimeout      = "COSMOVISOR_CONFIRMATION_TIMEOUT"
+	EnvConfirmationAbort        = "COSMOVISOR_CONFIRMATION_ABORT"
+	EnvMaxUpgradeSignals        = "COSMOVISOR_MAX_UPGRADE_SIGNALS"
+	EnvCallbackWatcherStopped   = "COSMOVISOR_CALLBACK_WATCHER_STOPPED"
+	EnvHTTPProxy                = "COSMOVISOR_HTTP_PROXY"
+	EnvNoProxy                  = "COSMOVISOR_NO_PROXY"
+	EnvChangeDetection          = "COSMOVISOR_CHANGE_DETECTION"
+	EnvExpandInfoEnv            = "COSMOVISOR_EXPAND_INFO_ENV"
+	EnvStatusTimeout            = "COSMOVISOR_STATUS_TIMEOUT"
+	EnvPendingInterval          = "COSMOVISOR_PENDING_INTERVAL"
+	EnvCallbackUpgradePending   = "COSMOVISOR_CALLBACK_UPGRADE_PENDING"
+	EnvCallbackDryRun           = "COSMOVISOR_CALLBACK_DRY_RUN"
+	EnvVerifyAppVersion         = "COSMOVISOR_VERIFY_APP_VERSION"
+	EnvScanOnStart              = "COSMOVISOR_SCAN_ON_START"
+	EnvHeightMilestones         = "COSMOVISOR_HEIGHT_MILESTONES"
+	EnvCallbackSecretFile       = "COSMOVISOR_CALLBACK_SECRET_FILE"
+	EnvRecaseExceptions         = "COSMOVISOR_RECASE_EXCEPTIONS"
+	EnvUpgradeInfoGlob          = "COSMOVISOR_UPGRADE_INFO_GLOB"
+	EnvHeightGracePeriod        = "COSMOVISOR_HEIGHT_GRACE_PERIOD"
+	EnvHeightFailurePolicy      = "COSMOVISOR_HEIGHT_FAILURE_POLICY"
+	EnvDecisionLog              = "COSMOVISOR_DECISION_LOG"
+	EnvUpgradeGateURL           = "COSMOVISOR_UPGRADE_GATE_URL"
+	EnvUpgradeGateTimeout       = "COSMOVISOR_UPGRADE_GATE_TIMEOUT"
+	EnvUpgradeGateFailOpen      = "COSMOVISOR_UPGRADE_GATE_FAIL_OPEN"
+	EnvStatusArgs               = "COSMOVISOR_STATUS_ARGS"
+	EnvQuarantineDir            = "COSMOVISOR_QUARANTINE_DIR"
+	EnvQuarantineAfter          = "COSMOVISOR_QUARANTINE_AFTER"
+	EnvMinFreeDiskBytes         = "COSMOVISOR_MIN_FREE_DISK_BYTES"
+	EnvNameVersionMap           = "COSMOVISOR_NAME_VERSION_MAP"
+	EnvStrictJSON               = "COSMOVISOR_STRICT_JSON"
+	EnvRestartHeuristicDelay    = "COSMOVISOR_RESTART_HEURISTIC_DELAY"
+	EnvJournaldEnabled          = "COSMOVISOR_JOURNALD_ENABLED"
+	EnvBinResolveRetries        = "COSMOVISOR_BIN_RESOLVE_RETRIES"
+	EnvBinResolveDelay          = "COSMOVISOR_BIN_RESOLVE_DELAY"
+	EnvCallbackAuth             = "COSMOVISOR_CALLBACK_AUTH"
+	EnvMinUpgradeInterval       = "COSMOVISOR_MIN_UPGRADE_INTERVAL"
+	EnvCallbackImminentBlocks   = "COSMOVISOR_CALLBACK_IMMINENT_BLOCKS"
+	EnvCallbackHeartbeat        = "COSMOVISOR_CALLBACK_HEARTBEAT_INTERVAL"
+	EnvInfoVarPrefix            = "COSMOVISOR_INFO_"
 )
 
+// log output formats
+const (
+	LogFormatText = "text"
+	LogFormatJSON = "json"
+)
+
+// upgrade-info.json change detection strategies
+const (
+	ChangeDetectionModTime = "modtime"
+	ChangeDetectionHash    = "hash"
+	ChangeDetectionBoth    = "both"
+)
+
+// policies applied when the block height cannot be read past the startup grace period
+const (
+	// HeightFailureOpen acts on the upgrade plan as if its height was reached
+	HeightFailureOpen = "open"
+	// HeightFailureClosed holds the upgrade until the height can be read again
+	HeightFailureClosed = "closed"
+)
+
+// policies applied when the post height reached hook fails
+const (
+	// HookFailureAbort doesn't upgrade, as when COSMOVISOR_CUSTOM_PREUPGRADE fails
+	HookFailureAbort = "abort"
+	// HookFailureContinue upgrades anyway
+	HookFailureContinue = "continue"
+)
+
+// confirmationFilename is the file an operator writes the upgrade name to in order to confirm it.
+const confirmationFilename = "upgrade-confirmed"
+
 const (
 	rootName    = "cosmovisor"
 	genesisDir  = "genesis"
@@ -60,11 +150,70 @@ type Config struct {
 	DisableLogs              bool
 	ColorLogs                bool
 	TimeFormatLogs           string
+	LogFormat                string
 	CustomPreupgrade         string
 	DisableRecase            bool
+	CallbackAPI              string
+	NodeID                   string
+	DeploymentID             string
+	EventEndpoints           map[CallbackEvent]string
+	CallbackTags             map[string]string
+	CallbackBreakerThreshold int
+	CallbackBreakerCooldown  time.Duration
+	MetricsAddr              string
+	MinFileAge               time.Duration
+	UpgradeInfoSchemaPath    string
+	LogThrottleInterval      time.Duration
+	VerifyHeightReached      bool
+	HeightTolerance          int64
+	PostHeightReachedHook    string
+	PostHeightReachedTimeout time.Duration
+	PostHeightReachedPolicy  string
+	PlatformPreference       []string
+	RequireConfirmation      bool
+	ConfirmationTimeout      time.Duration
+	ConfirmationAbort        bool
+	MaxUpgradeSignals        int
+	CallbackWatcherStopped   bool
+	HTTPProxy                string
+	NoProxy                  string
+	ChangeDetection          string
+	ExpandInfoEnv            bool
+	StatusTimeout            time.Duration
+	PendingInterval          time.Duration
+	CallbackUpgradePending   bool
+	CallbackDryRun           bool
+	VerifyAppVersion         bool
+	ScanOnStart              bool
+	HeightMilestones         []int64
+	CallbackSecretFile       string
+	RecaseExceptions         []string
+	UpgradeInfoGlob          string
+	HeightGracePeriod        time.Duration
+	HeightFailurePolicy      string
+	DecisionLogPath          string
+	UpgradeGateURL           string
+	UpgradeGateTimeout       time.Duration
+	UpgradeGateFailOpen      bool
+	StatusArgs               []string
+	QuarantineDir            string
+	QuarantineAfter          int
+	MinFreeDiskBytes         int64
+	NameVersionMap           map[string]VersionRef
+	StrictJSON               bool
+	RestartHeuristicDelay    time.Duration
+	JournaldEnabled          bool
+	BinResolveRetries        int
+	BinResolveDelay          time.Duration
+	CallbackAuth             map[CallbackEvent]CallbackAuth
+	MinUpgradeInterval       time.Duration
+	CallbackImminentBlocks   int64
+	CallbackHeartbeat        time.Duration
 
 	// currently running upgrade
 	currentUpgrade upgradetypes.Plan
+	// callbackSecret is the secret loaded from CallbackSecretFile
+	callbackSecret string
 }
 
 // Root returns the root directory where all info lives
@@ -98,6 +247,67 @@ func (cfg *Config) UpgradeInfoFilePath() string {
 	return filepath.Join(cfg.Home, "data", upgradetypes.UpgradeInfoFilename)
 }
 
+// UpgradeInfoGlobPattern is the pattern of the upgrade-info files watched when UpgradeInfoGlob
+// is set, relative patterns being matched in the directory of upgrade-info.json.
+func (cfg *Config) UpgradeInfoGlobPattern() string {
+	if cfg.UpgradeInfoGlob == "" || filepath.IsAbs(cfg.UpgradeInfoGlob) {
+		return cfg.UpgradeInfoGlob
+	}
+
+	return filepath.Join(filepath.Dir(cfg.UpgradeInfoFilePath()), cfg.UpgradeInfoGlob)
+}
+
+// CurrentUpgradeFilePath is the file cosmovisor records the last applied upgrade in: the
+// upgrade-info.json written in the upgrade directory by SetCurrentUpgrade, read through the
+// current link.
+func (cfg *Config) CurrentUpgradeFilePath() string {
+	return filepath.Join(cfg.Root(), currentLink, upgradetypes.UpgradeInfoFilename)
+}
+
+// UpgradeInfoParseOptions are the options upgrade-info.json is parsed with.
+func (cfg *Config) UpgradeInfoParseOptions() []ParseOption {
+	opts := []ParseOption{
+		ParseOptionDisableRecase(cfg.DisableRecase),
+		ParseOptionRecaseExceptions(cfg.RecaseExceptions),
+		ParseOptionStrictJSON(cfg.StrictJSON),
+	}
+	if cfg.ExpandInfoEnv {
+		opts = append(opts, ParseOptionExpandInfo(LookupInfoEnv))
+	}
+
+	return opts
+}
+
+// StateFilePath is the file the upgrade watcher persists its state in.
+func (cfg *Config) StateFilePath() string {
+	return filepath.Join(cfg.Root(), stateFilename)
+}
+
+// ConfirmationFilePath is the file an operator writes the upgrade name to in order to confirm it.
+func (cfg *Config) ConfirmationFilePath() string {
+	return filepath.Join(cfg.Root(), confirmationFilename)
+}
+
+// PostHeightReachedHookPath is the script run once the upgrade height is reached, or an empty
+// string if none is configured.
+func (cfg *Config) PostHeightReachedHookPath() string {
+	if cfg.PostHeightReachedHook == "" {
+		return ""
+	}
+
+	return filepath.Join(cfg.Root(), cfg.PostHeightReachedHook)
+}
+
+// CallbackBaseURL is the URL the upgrade callbacks are posted under, or an empty string if
+// no callback API is configured.
+func (cfg *Config) CallbackBaseURL() string {
+	if cfg.CallbackAPI == "" {
+		return ""
+	}
+
+	return cfg.CallbackAPI + "/internal/cosmos/" + cfg.NodeID + "/" + cfg.DeploymentID
+}
+
 // SymLinkToGenesis creates a symbolic link from "./current" to the genesis directory.
 func (cfg *Config) SymLinkToGenesis() (string, error) {
 	genesis := filepath.Join(cfg.Root(), genesisDir)
@@ -145,6 +355,17 @@ func (cfg *Config) CurrentBin() (string, error) {
 	return binpath, nil
 }
 
+// currentLinkBin returns the binary the current symlink points to. Unlike CurrentBin, it has no
+// side effect: a missing current symlink is not replaced by a link to genesis.
+func (cfg *Config) currentLinkBin() (string, error) {
+	dest, err := os.Readlink(filepath.Join(cfg.Root(), currentLink))
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(dest, "bin", cfg.Name), nil
+}
+
 // GetConfigFromEnv will read the environmental variables into a config
 // and then validate it is reasonable
 func GetConfigFromEnv() (*Config, error) {
@@ -154,6 +375,22 @@ func GetConfigFromEnv() (*Config, error) {
 		Name:             os.Getenv(EnvName),
 		DataBackupPath:   os.Getenv(EnvDataBackupPath),
 		CustomPreupgrade: os.Getenv(EnvCustomPreupgrade),
+		CallbackAPI:      os.Getenv(EnvCallbackAPI),
+		NodeID:           os.Getenv(EnvNodeID),
+		DeploymentID:     os.Getenv(EnvDeploymentID),
+		MetricsAddr:      os.Getenv(EnvMetricsAddr),
+
+		PostHeightReachedHook: os.Getenv(EnvPostHeightReachedHook),
+		HTTPProxy:             os.Getenv(EnvHTTPProxy),
+		NoProxy:               os.Getenv(EnvNoProxy),
+
+		UpgradeInfoSchemaPath: os.Getenv(EnvUpgradeInfoSchema),
+		CallbackSecretFile:    os.Getenv(EnvCallbackSecretFile),
+		RecaseExceptions:      parseEnvList(os.Getenv(EnvRecaseExceptions)),
+		UpgradeInfoGlob:       os.Getenv(EnvUpgradeInfoGlob),
+		DecisionLogPath:       os.Getenv(EnvDecisionLog),
+		UpgradeGateURL:        os.Getenv(EnvUpgradeGateURL),
+		QuarantineDir:         os.Getenv(EnvQuarantineDir),
 	}
 
 	if cfg.DataBackupPath == "" {
@@ -182,9 +419,57 @@ func GetConfigFromEnv() (*Config, error) {
 	if cfg.TimeFormatLogs, err = TimeFormatOptionFromEnv(EnvTimeFormatLogs, time.Kitchen); err != nil {
 		errs = append(errs, err)
 	}
+	if cfg.LogFormat, err = LogFormatOptionFromEnv(EnvLogFormat, LogFormatText); err != nil {
+		errs = append(errs, err)
+	}
+	if cfg.ChangeDetection, err = ChangeDetectionOptionFromEnv(EnvChangeDetection, ChangeDetectionModTime); err != nil {
+		errs = append(errs, err)
+	}
+	if cfg.HeightFailurePolicy, err = HeightFailurePolicyOptionFromEnv(EnvHeightFailurePolicy, HeightFailureOpen); err != nil {
+		errs = append(errs, err)
+	}
+	if cfg.PostHeightReachedPolicy, err = HookFailurePolicyOptionFromEnv(EnvPostHeightReachedPolicy, HookFailureAbort); err != nil {
+		errs = append(errs, err)
+	}
 	if cfg.DisableRecase, err = BooleanOption(EnvDisableRecase, false); err != nil {
 		errs = append(errs, err)
 	}
+	if cfg.VerifyHeightReached, err = BooleanOption(EnvVerifyHeightReached, false); err != nil {
+		errs = append(errs, err)
+	}
+	if cfg.UpgradeGateFailOpen, err = BooleanOption(EnvUpgradeGateFailOpen, false); err != nil {
+		errs = append(errs, err)
+	}
+	if cfg.RequireConfirmation, err = BooleanOption(EnvRequireConfirmation, false); err != nil {
+		errs = append(errs, err)
+	}
+	if cfg.ConfirmationAbort, err = BooleanOption(EnvConfirmationAbort, false); err != nil {
+		errs = append(errs, err)
+	}
+	if cfg.CallbackWatcherStopped, err = BooleanOption(EnvCallbackWatcherStopped, false); err != nil {
+		errs = append(errs, err)
+	}
+	if cfg.CallbackUpgradePending, err = BooleanOption(EnvCallbackUpgradePending, false); err != nil {
+		errs = append(errs, err)
+	}
+	if cfg.CallbackDryRun, err = BooleanOption(EnvCallbackDryRun, false); err != nil {
+		errs = append(errs, err)
+	}
+	if cfg.VerifyAppVersion, err = BooleanOption(EnvVerifyAppVersion, false); err != nil {
+		errs = append(errs, err)
+	}
+	if cfg.ScanOnStart, err = BooleanOption(EnvScanOnStart, true); err != nil {
+		errs = append(errs, err)
+	}
+	if cfg.ExpandInfoEnv, err = BooleanOption(EnvExpandInfoEnv, false); err != nil {
+		errs = append(errs, err)
+	}
+	if cfg.StrictJSON, err = BooleanOption(EnvStrictJSON, false); err != nil {
+		errs = append(errs, err)
+	}
+	if cfg.JournaldEnabled, err = BooleanOption(EnvJournaldEnabled, false); err != nil {
+		errs = append(errs, err)
+	}
 
 	interval := os.Getenv(EnvInterval)
 	if interval != "" {
@@ -220,6 +505,246 @@ func GetConfigFromEnv() (*Config, error) {
 		}
 	}
 
+	if nameVersionMap := os.Getenv(EnvNameVersionMap); nameVersionMap != "" {
+		val, err := parseNameVersionMap(nameVersionMap)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("invalid: %s: %w", EnvNameVersionMap, err))
+		} else {
+			cfg.NameVersionMap = val
+		}
+	}
+
+	if callbackEndpoints := os.Getenv(EnvCallbackEndpoints); callbackEndpoints != "" {
+		val, err := parseCallbackEndpoints(callbackEndpoints)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("invalid: %s: %w", EnvCallbackEndpoints, err))
+		} else {
+			cfg.EventEndpoints = val
+		}
+	}
+
+	if cfg.CallbackSecretFile != "" {
+		if cfg.callbackSecret, err = loadSecret(cfg.CallbackSecretFile); err != nil {
+			errs = append(errs, fmt.Errorf("invalid: %s: %w", EnvCallbackSecretFile, err))
+		}
+	}
+
+	if callbackAuth := os.Getenv(EnvCallbackAuth); callbackAuth != "" {
+		if val, err := parseCallbackAuth(callbackAuth); err != nil {
+			errs = append(errs, fmt.Errorf("invalid: %s: %w", EnvCallbackAuth, err))
+		} else {
+			for event, auth := range val {
+				if val[event], err = auth.load(); err != nil {
+					errs = append(errs, fmt.Errorf("invalid: %s: %s: %w", EnvCallbackAuth, event, err))
+				}
+			}
+			cfg.CallbackAuth = val
+		}
+	}
+
+	if callbackTags := os.Getenv(EnvCallbackTags); callbackTags != "" {
+		val, err := parseEnvMap(callbackTags)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("invalid: %s: %w", EnvCallbackTags, err))
+		} else {
+			cfg.CallbackTags = val
+		}
+	}
+
+	if minFileAge := os.Getenv(EnvMinFileAge); minFileAge != "" {
+		val, err := parseEnvDuration(minFileAge)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("invalid: %s: %w", EnvMinFileAge, err))
+		} else {
+			cfg.MinFileAge = val
+		}
+	}
+
+	if logThrottleInterval := os.Getenv(EnvLogThrottleInterval); logThrottleInterval != "" {
+		val, err := parseEnvDuration(logThrottleInterval)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("invalid: %s: %w", EnvLogThrottleInterval, err))
+		} else {
+			cfg.LogThrottleInterval = val
+		}
+	}
+
+	if postHeightReachedTimeout := os.Getenv(EnvPostHeightReachedTimeout); postHeightReachedTimeout != "" {
+		val, err := parseEnvDuration(postHeightReachedTimeout)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("invalid: %s: %w", EnvPostHeightReachedTimeout, err))
+		} else {
+			cfg.PostHeightReachedTimeout = val
+		}
+	}
+
+	if cfg.HTTPProxy != "" {
+		if _, err := url.Parse(cfg.HTTPProxy); err != nil {
+			errs = append(errs, fmt.Errorf("invalid: %s: %w", EnvHTTPProxy, err))
+		}
+	}
+
+	if statusTimeout := os.Getenv(EnvStatusTimeout); statusTimeout != "" {
+		val, err := parseEnvDuration(statusTimeout)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("invalid: %s: %w", EnvStatusTimeout, err))
+		} else {
+			cfg.StatusTimeout = val
+		}
+	}
+
+	if cfg.UpgradeGateURL != "" {
+		if _, err := url.Parse(cfg.UpgradeGateURL); err != nil {
+			errs = append(errs, fmt.Errorf("invalid: %s: %w", EnvUpgradeGateURL, err))
+		}
+	}
+
+	if statusArgs := os.Getenv(EnvStatusArgs); statusArgs != "" {
+		cfg.StatusArgs = strings.Fields(statusArgs)
+	}
+
+	if upgradeGateTimeout := os.Getenv(EnvUpgradeGateTimeout); upgradeGateTimeout != "" {
+		val, err := parseEnvDuration(upgradeGateTimeout)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("invalid: %s: %w", EnvUpgradeGateTimeout, err))
+		} else {
+			cfg.UpgradeGateTimeout = val
+		}
+	}
+
+	if heightGracePeriod := os.Getenv(EnvHeightGracePeriod); heightGracePeriod != "" {
+		val, err := parseEnvDuration(heightGracePeriod)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("invalid: %s: %w", EnvHeightGracePeriod, err))
+		} else {
+			cfg.HeightGracePeriod = val
+		}
+	}
+
+	if minUpgradeInterval := os.Getenv(EnvMinUpgradeInterval); minUpgradeInterval != "" {
+		val, err := parseEnvDuration(minUpgradeInterval)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("invalid: %s: %w", EnvMinUpgradeInterval, err))
+		} else {
+			cfg.MinUpgradeInterval = val
+		}
+	}
+
+	imminentBlocks := os.Getenv(EnvCallbackImminentBlocks)
+	if cfg.CallbackImminentBlocks, err = strconv.ParseInt(imminentBlocks, 10, 64); err != nil && imminentBlocks != "" {
+		errs = append(errs, fmt.Errorf("%s could not be parsed to int: %w", EnvCallbackImminentBlocks, err))
+	}
+
+	if heartbeat := os.Getenv(EnvCallbackHeartbeat); heartbeat != "" {
+		val, err := parseEnvDuration(heartbeat)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("invalid: %s: %w", EnvCallbackHeartbeat, err))
+		} else {
+			cfg.CallbackHeartbeat = val
+		}
+	}
+
+	if restartHeuristicDelay := os.Getenv(EnvRestartHeuristicDelay); restartHeuristicDelay != "" {
+		val, err := parseEnvDuration(restartHeuristicDelay)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("invalid: %s: %w", EnvRestartHeuristicDelay, err))
+		} else {
+			cfg.RestartHeuristicDelay = val
+		}
+	}
+
+	if pendingInterval := os.Getenv(EnvPendingInterval); pendingInterval != "" {
+		val, err := parseEnvDuration(pendingInterval)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("invalid: %s: %w", EnvPendingInterval, err))
+		} else {
+			cfg.PendingInterval = val
+		}
+	}
+
+	if confirmationTimeout := os.Getenv(EnvConfirmationTimeout); confirmationTimeout != "" {
+		val, err := parseEnvDuration(confirmationTimeout)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("invalid: %s: %w", EnvConfirmationTimeout, err))
+		} else {
+			cfg.ConfirmationTimeout = val
+		}
+	}
+
+	if cfg.UpgradeInfoGlob != "" {
+		if _, err := filepath.Match(cfg.UpgradeInfoGlob, ""); err != nil {
+			errs = append(errs, fmt.Errorf("invalid: %s: %w", EnvUpgradeInfoGlob, err))
+		}
+	}
+
+	if heightMilestones := os.Getenv(EnvHeightMilestones); heightMilestones != "" {
+		val, err := parseHeightMilestones(heightMilestones)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("invalid: %s: %w", EnvHeightMilestones, err))
+		} else {
+			cfg.HeightMilestones = val
+		}
+	}
+
+	if platformPreference := os.Getenv(EnvPlatformPreference); platformPreference != "" {
+		val, err := parsePlatformPreference(platformPreference)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("invalid: %s: %w", EnvPlatformPreference, err))
+		} else {
+			cfg.PlatformPreference = val
+		}
+	}
+
+	minFreeDiskBytes := os.Getenv(EnvMinFreeDiskBytes)
+	if cfg.MinFreeDiskBytes, err = strconv.ParseInt(minFreeDiskBytes, 10, 64); err != nil && minFreeDiskBytes != "" {
+		errs = append(errs, fmt.Errorf("%s could not be parsed to int: %w", EnvMinFreeDiskBytes, err))
+	}
+
+	heightTolerance := os.Getenv(EnvHeightTolerance)
+	if cfg.HeightTolerance, err = strconv.ParseInt(heightTolerance, 10, 64); err != nil && heightTolerance != "" {
+		errs = append(errs, fmt.Errorf("%s could not be parsed to int: %w", EnvHeightTolerance, err))
+	}
+
+	maxUpgradeSignals := os.Getenv(EnvMaxUpgradeSignals)
+	if cfg.MaxUpgradeSignals, err = strconv.Atoi(maxUpgradeSignals); err != nil && maxUpgradeSignals != "" {
+		errs = append(errs, fmt.Errorf("%s could not be parsed to int: %w", EnvMaxUpgradeSignals, err))
+	}
+
+	callbackBreakerThreshold := os.Getenv(EnvCallbackBreakerThreshold)
+	if cfg.CallbackBreakerThreshold, err = strconv.Atoi(callbackBreakerThreshold); err != nil && callbackBreakerThreshold != "" {
+		errs = append(errs, fmt.Errorf("%s could not be parsed to int: %w", EnvCallbackBreakerThreshold, err))
+	}
+
+	quarantineAfter := os.Getenv(EnvQuarantineAfter)
+	if cfg.QuarantineAfter, err = strconv.Atoi(quarantineAfter); err != nil && quarantineAfter != "" {
+		errs = append(errs, fmt.Errorf("%s could not be parsed to int: %w", EnvQuarantineAfter, err))
+	}
+
+	binResolveRetries := os.Getenv(EnvBinResolveRetries)
+	if cfg.BinResolveRetries, err = strconv.Atoi(binResolveRetries); err != nil && binResolveRetries != "" {
+		errs = append(errs, fmt.Errorf("%s could not be parsed to int: %w", EnvBinResolveRetries, err))
+	} else if cfg.BinResolveRetries < 0 {
+		errs = append(errs, fmt.Errorf("%s must not be negative", EnvBinResolveRetries))
+	}
+
+	if binResolveDelay := os.Getenv(EnvBinResolveDelay); binResolveDelay != "" {
+		val, err := parseEnvDuration(binResolveDelay)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("invalid: %s: %w", EnvBinResolveDelay, err))
+		} else {
+			cfg.BinResolveDelay = val
+		}
+	}
+
+	if callbackBreakerCooldown := os.Getenv(EnvCallbackBreakerCooldown); callbackBreakerCooldown != "" {
+		val, err := parseEnvDuration(callbackBreakerCooldown)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("invalid: %s: %w", EnvCallbackBreakerCooldown, err))
+		} else {
+			cfg.CallbackBreakerCooldown = val
+		}
+	}
+
 	envPreupgradeMaxRetriesVal := os.Getenv(EnvPreupgradeMaxRetries)
 	if cfg.PreupgradeMaxRetries, err = strconv.Atoi(envPreupgradeMaxRetriesVal); err != nil && envPreupgradeMaxRetriesVal != "" {
 		errs = append(errs, fmt.Errorf("%s could not be parsed to int: %w", EnvPreupgradeMaxRetries, err))
@@ -239,9 +764,15 @@ func (cfg *Config) Logger(dst io.Writer) log.Logger {
 	if cfg.DisableLogs {
 		logger = log.NewNopLogger()
 	} else {
-		logger = log.NewLogger(dst,
+		opts := []log.Option{
 			log.ColorOption(cfg.ColorLogs),
-			log.TimeFormatOption(cfg.TimeFormatLogs)).With(log.ModuleKey, "cosmovisor")
+			log.TimeFormatOption(cfg.TimeFormatLogs),
+		}
+		if cfg.LogFormat == LogFormatJSON {
+			opts = append(opts, log.OutputJSONOption())
+		}
+
+		logger = log.NewLogger(dst, opts...).With(log.ModuleKey, "cosmovisor")
 	}
 
 	return logger
@@ -360,7 +891,7 @@ func (cfg *Config) UpgradeInfo() (upgradetypes.Plan, error) {
 		return cfg.currentUpgrade, nil
 	}
 
-	filename := filepath.Join(cfg.Root(), currentLink, upgradetypes.UpgradeInfoFilename)
+	filename := cfg.CurrentUpgradeFilePath()
 	_, err := os.Lstat(filename)
 	var u upgradetypes.Plan
 	var bz []byte
@@ -432,6 +963,54 @@ func TimeFormatOptionFromEnv(env, defaultVal string) (string, error) {
 	return "", fmt.Errorf("env variable %q must have a timeformat value (\"layout|ansic|unixdate|rubydate|rfc822|rfc822z|rfc850|rfc1123|rfc1123z|rfc3339|rfc3339nano|kitchen\"), got %q", EnvTimeFormatLogs, val)
 }
 
+// checks and validates env option
+func LogFormatOptionFromEnv(env, defaultVal string) (string, error) {
+	switch val := strings.ToLower(os.Getenv(env)); val {
+	case "":
+		return defaultVal, nil
+	case LogFormatText, LogFormatJSON:
+		return val, nil
+	default:
+		return "", fmt.Errorf("env variable %q must have a log format value (\"%s|%s\"), got %q", env, LogFormatText, LogFormatJSON, val)
+	}
+}
+
+// checks and validates env option
+func ChangeDetectionOptionFromEnv(env, defaultVal string) (string, error) {
+	switch val := strings.ToLower(os.Getenv(env)); val {
+	case "":
+		return defaultVal, nil
+	case ChangeDetectionModTime, ChangeDetectionHash, ChangeDetectionBoth:
+		return val, nil
+	default:
+		return "", fmt.Errorf("env variable %q must have a change detection value (\"%s|%s|%s\"), got %q", env, ChangeDetectionModTime, ChangeDetectionHash, ChangeDetectionBoth, val)
+	}
+}
+
+// checks and validates env option
+func HeightFailurePolicyOptionFromEnv(env, defaultVal string) (string, error) {
+	switch val := strings.ToLower(os.Getenv(env)); val {
+	case "":
+		return defaultVal, nil
+	case HeightFailureOpen, HeightFailureClosed:
+		return val, nil
+	default:
+		return "", fmt.Errorf("env variable %q must have a height failure policy value (\"%s|%s\"), got %q", env, HeightFailureOpen, HeightFailureClosed, val)
+	}
+}
+
+// checks and validates env option
+func HookFailurePolicyOptionFromEnv(env, defaultVal string) (string, error) {
+	switch val := strings.ToLower(os.Getenv(env)); val {
+	case "":
+		return defaultVal, nil
+	case HookFailureAbort, HookFailureContinue:
+		return val, nil
+	default:
+		return "", fmt.Errorf("env variable %q must have a hook failure policy value (\"%s|%s\"), got %q", env, HookFailureAbort, HookFailureContinue, val)
+	}
+}
+
 // DetailString returns a multi-line string with details about this config.
 func (cfg Config) DetailString() string {
 	configEntries := []struct{ name, value string }{
@@ -449,8 +1028,65 @@ func (cfg Config) DetailString() string {
 		{EnvDisableLogs, fmt.Sprintf("%t", cfg.DisableLogs)},
 		{EnvColorLogs, fmt.Sprintf("%t", cfg.ColorLogs)},
 		{EnvTimeFormatLogs, cfg.TimeFormatLogs},
+		{EnvLogFormat, cfg.LogFormat},
 		{EnvCustomPreupgrade, cfg.CustomPreupgrade},
 		{EnvDisableRecase, fmt.Sprintf("%t", cfg.DisableRecase)},
+		{EnvCallbackAPI, cfg.CallbackAPI},
+		{EnvNodeID, cfg.NodeID},
+		{EnvDeploymentID, cfg.DeploymentID},
+		{EnvCallbackEndpoints, formatCallbackEndpoints(cfg.EventEndpoints)},
+		{EnvCallbackTags, formatEnvMap(cfg.CallbackTags)},
+		{EnvCallbackBreakerThreshold, fmt.Sprintf("%d", cfg.CallbackBreakerThreshold)},
+		{EnvCallbackBreakerCooldown, cfg.CallbackBreakerCooldown.String()},
+		{EnvMetricsAddr, cfg.MetricsAddr},
+		{EnvMinFileAge, cfg.MinFileAge.String()},
+		{EnvUpgradeInfoSchema, cfg.UpgradeInfoSchemaPath},
+		{EnvLogThrottleInterval, cfg.LogThrottleInterval.String()},
+		{EnvVerifyHeightReached, fmt.Sprintf("%t", cfg.VerifyHeightReached)},
+		{EnvHeightTolerance, fmt.Sprintf("%d", cfg.HeightTolerance)},
+		{EnvPostHeightReachedHook, cfg.PostHeightReachedHook},
+		{EnvPostHeightReachedTimeout, cfg.PostHeightReachedTimeout.String()},
+		{EnvPostHeightReachedPolicy, cfg.PostHeightReachedPolicy},
+		{EnvPlatformPreference, strings.Join(cfg.PlatformPreference, ",")},
+		{EnvRequireConfirmation, fmt.Sprintf("%t", cfg.RequireConfirmation)},
+		{EnvConfirmationTimeout, cfg.ConfirmationTimeout.String()},
+		{EnvConfirmationAbort, fmt.Sprintf("%t", cfg.ConfirmationAbort)},
+		{EnvMaxUpgradeSignals, fmt.Sprintf("%d", cfg.MaxUpgradeSignals)},
+		{EnvCallbackWatcherStopped, fmt.Sprintf("%t", cfg.CallbackWatcherStopped)},
+		{EnvHTTPProxy, cfg.HTTPProxy},
+		{EnvNoProxy, cfg.NoProxy},
+		{EnvChangeDetection, cfg.ChangeDetection},
+		{EnvExpandInfoEnv, fmt.Sprintf("%t", cfg.ExpandInfoEnv)},
+		{EnvStatusTimeout, cfg.StatusTimeout.String()},
+		{EnvPendingInterval, cfg.PendingInterval.String()},
+		{EnvCallbackUpgradePending, fmt.Sprintf("%t", cfg.CallbackUpgradePending)},
+		{EnvCallbackDryRun, fmt.Sprintf("%t", cfg.CallbackDryRun)},
+		{EnvVerifyAppVersion, fmt.Sprintf("%t", cfg.VerifyAppVersion)},
+		{EnvScanOnStart, fmt.Sprintf("%t", cfg.ScanOnStart)},
+		{EnvHeightMilestones, formatHeightMilestones(cfg.HeightMilestones)},
+		{EnvCallbackSecretFile, cfg.CallbackSecretFile},
+		{EnvRecaseExceptions, strings.Join(cfg.RecaseExceptions, ",")},
+		{EnvUpgradeInfoGlob, cfg.UpgradeInfoGlob},
+		{EnvHeightGracePeriod, cfg.HeightGracePeriod.String()},
+		{EnvHeightFailurePolicy, cfg.HeightFailurePolicy},
+		{EnvDecisionLog, cfg.DecisionLogPath},
+		{EnvUpgradeGateURL, cfg.UpgradeGateURL},
+		{EnvUpgradeGateTimeout, cfg.UpgradeGateTimeout.String()},
+		{EnvUpgradeGateFailOpen, fmt.Sprintf("%t", cfg.UpgradeGateFailOpen)},
+		{EnvStatusArgs, strings.Join(cfg.StatusArgs, " ")},
+		{EnvQuarantineDir, cfg.QuarantineDir},
+		{EnvQuarantineAfter, fmt.Sprintf("%d", cfg.QuarantineAfter)},
+		{EnvMinFreeDiskBytes, fmt.Sprintf("%d", cfg.MinFreeDiskBytes)},
+		{EnvNameVersionMap, formatNameVersionMap(cfg.NameVersionMap)},
+		{EnvStrictJSON, fmt.Sprintf("%t", cfg.StrictJSON)},
+		{EnvRestartHeuristicDelay, cfg.RestartHeuristicDelay.String()},
+		{EnvJournaldEnabled, fmt.Sprintf("%t", cfg.JournaldEnabled)},
+		{EnvBinResolveRetries, fmt.Sprintf("%d", cfg.BinResolveRetries)},
+		{EnvBinResolveDelay, cfg.BinResolveDelay.String()},
+		{EnvCallbackAuth, formatCallbackAuth(cfg.CallbackAuth)},
+		{EnvMinUpgradeInterval, cfg.MinUpgradeInterval.String()},
+		{EnvCallbackImminentBlocks, fmt.Sprintf("%d", cfg.CallbackImminentBlocks)},
+		{EnvCallbackHeartbeat, cfg.CallbackHeartbeat.String()},
 	}
 
 	derivedEntries := []struct{ name, value string }{
@@ -459,6 +1095,7 @@ func (cfg Config) DetailString() string {
 		{"Genesis Bin", cfg.GenesisBin()},
 		{"Monitored File", cfg.UpgradeInfoFilePath()},
 		{"Data Backup Dir", cfg.DataBackupPath},
+		{"Callback URL", cfg.CallbackBaseURL()},
 	}
 
 	var sb strings.Builder
@@ -1,9 +1,12 @@
 package cosmovisor
 
 import (
+	"bytes"
+	"encoding/json"
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
@@ -11,6 +14,7 @@ import (
 	"github.com/stretchr/testify/require"
 	"github.com/stretchr/testify/suite"
 
+	"cosmossdk.io/log"
 	"cosmossdk.io/x/upgrade/plan"
 )
 
@@ -393,6 +397,154 @@ func (s *argsTestSuite) TestTimeFormat() {
 	check(time.Kitchen, time.Kitchen, false, "should handle kitchen value")
 }
 
+func (s *argsTestSuite) TestLogFormat() {
+	initialEnv := s.clearEnv()
+	defer s.setEnv(nil, initialEnv)
+
+	name := "COSMOVISOR_TEST_VAL"
+
+	check := func(expected string, isErr bool, msg string) {
+		v, err := LogFormatOptionFromEnv(name, LogFormatText)
+		if isErr {
+			s.Require().Error(err)
+			return
+		}
+		s.Require().NoError(err)
+		s.Require().Equal(expected, v, msg)
+	}
+
+	os.Unsetenv(name)
+	check(LogFormatText, false, "should correctly set default value")
+
+	os.Setenv(name, "wrong")
+	check("", true, "should error on wrong value")
+
+	os.Setenv(name, "text")
+	check(LogFormatText, false, "should handle text value")
+	os.Setenv(name, "JSON")
+	check(LogFormatJSON, false, "should handle json value")
+}
+
+func (s *argsTestSuite) TestParsePlatformPreference() {
+	platforms, err := parsePlatformPreference(" linux/arm64, linux/amd64 ,any,")
+	s.Require().NoError(err)
+	s.Require().Equal([]string{"linux/arm64", "linux/amd64", "any"}, platforms)
+
+	_, err = parsePlatformPreference("linux")
+	s.Require().Error(err)
+	_, err = parsePlatformPreference("linux/")
+	s.Require().Error(err)
+}
+
+func (s *argsTestSuite) TestChangeDetection() {
+	initialEnv := s.clearEnv()
+	defer s.setEnv(nil, initialEnv)
+
+	name := "COSMOVISOR_TEST_VAL"
+
+	check := func(expected string, isErr bool, msg string) {
+		v, err := ChangeDetectionOptionFromEnv(name, ChangeDetectionModTime)
+		if isErr {
+			s.Require().Error(err)
+			return
+		}
+		s.Require().NoError(err)
+		s.Require().Equal(expected, v, msg)
+	}
+
+	os.Unsetenv(name)
+	check(ChangeDetectionModTime, false, "should correctly set default value")
+
+	os.Setenv(name, "size")
+	check("", true, "should error on wrong value")
+
+	os.Setenv(name, "hash")
+	check(ChangeDetectionHash, false, "should handle hash value")
+	os.Setenv(name, "Both")
+	check(ChangeDetectionBoth, false, "should handle both value")
+}
+
+func (s *argsTestSuite) TestHeightFailurePolicy() {
+	initialEnv := s.clearEnv()
+	defer s.setEnv(nil, initialEnv)
+
+	name := "COSMOVISOR_TEST_VAL"
+
+	check := func(expected string, isErr bool, msg string) {
+		v, err := HeightFailurePolicyOptionFromEnv(name, HeightFailureOpen)
+		if isErr {
+			s.Require().Error(err)
+			return
+		}
+		s.Require().NoError(err)
+		s.Require().Equal(expected, v, msg)
+	}
+
+	os.Unsetenv(name)
+	check(HeightFailureOpen, false, "should correctly set default value")
+
+	os.Setenv(name, "halt")
+	check("", true, "should error on wrong value")
+
+	os.Setenv(name, "Closed")
+	check(HeightFailureClosed, false, "should handle closed value")
+}
+
+func (s *argsTestSuite) TestHookFailurePolicy() {
+	initialEnv := s.clearEnv()
+	defer s.setEnv(nil, initialEnv)
+
+	name := "COSMOVISOR_TEST_VAL"
+
+	check := func(expected string, isErr bool, msg string) {
+		v, err := HookFailurePolicyOptionFromEnv(name, HookFailureAbort)
+		if isErr {
+			s.Require().Error(err)
+			return
+		}
+		s.Require().NoError(err)
+		s.Require().Equal(expected, v, msg)
+	}
+
+	os.Unsetenv(name)
+	check(HookFailureAbort, false, "should correctly set default value")
+
+	os.Setenv(name, "ignore")
+	check("", true, "should error on wrong value")
+
+	os.Setenv(name, "Continue")
+	check(HookFailureContinue, false, "should handle continue value")
+}
+
+func (s *argsTestSuite) TestLoggerJSON() {
+	var buf bytes.Buffer
+	cfg := &Config{LogFormat: LogFormatJSON, TimeFormatLogs: time.RFC3339}
+	logger := cfg.Logger(&buf)
+
+	logger.Info("upgrade detected", "name", "chain2", "height", 49)
+	logger.Error("upgrade callback failed", "event", CallbackEventReached)
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	s.Require().Len(lines, 2)
+	for _, line := range lines {
+		var entry map[string]any
+		s.Require().NoError(json.Unmarshal([]byte(line), &entry), line)
+		s.Require().Equal("cosmovisor", entry[log.ModuleKey])
+		s.Require().Contains(entry, "level")
+		s.Require().Contains(entry, "message")
+		s.Require().Contains(entry, "time")
+	}
+	s.Require().Contains(lines[0], `"name":"chain2"`)
+	s.Require().Contains(lines[0], `"height":49`)
+	s.Require().Contains(lines[1], `"event":"reached"`)
+
+	// text remains the default
+	buf.Reset()
+	cfg = &Config{LogFormat: LogFormatText}
+	cfg.Logger(&buf).Info("upgrade detected")
+	s.Require().Error(json.Unmarshal(buf.Bytes(), &map[string]any{}))
+}
+
 func (s *argsTestSuite) TestDetailString() {
 	home := "/home"
 	name := "test-name"
@@ -481,6 +633,11 @@ func (s *argsTestSuite) TestGetConfigFromEnv() {
 			DisableLogs:              disableLogs,
 			ColorLogs:                colorLogs,
 			TimeFormatLogs:           timeFormatLogs,
+			LogFormat:                LogFormatText,
+			ChangeDetection:          ChangeDetectionModTime,
+			HeightFailurePolicy:      HeightFailureOpen,
+			PostHeightReachedPolicy:  HookFailureAbort,
+			ScanOnStart:              true,
 			CustomPreupgrade:         customPreUpgrade,
 			DisableRecase:            disableRecase,
 			ShutdownGrace:            time.Duration(shutdownGrace),
@@ -806,3 +963,73 @@ func BenchmarkDetailString(b *testing.B) {
 	// Otherwise reset the sink.
 	sink = (interface{})(nil)
 }
+
+func (s *argsTestSuite) TestGetConfigFromEnvCallbackSecret() {
+	initialEnv := s.clearEnv()
+	defer s.setEnv(nil, initialEnv)
+	defer os.Unsetenv(EnvCallbackSecretFile)
+
+	absPath, err := filepath.Abs(filepath.Join("testdata", "validate"))
+	s.Require().NoError(err)
+	s.Require().NoError(os.Setenv(EnvHome, absPath))
+	s.Require().NoError(os.Setenv(EnvName, "testname"))
+
+	secretFile := filepath.Join(s.T().TempDir(), "callback-secret")
+	s.Require().NoError(os.WriteFile(secretFile, []byte("s3cr3t\n"), 0o600))
+	s.Require().NoError(os.Setenv(EnvCallbackSecretFile, "file://"+secretFile))
+	cfg, err := GetConfigFromEnv()
+	s.Require().NoError(err)
+	s.Require().Equal("s3cr3t", cfg.callbackSecret)
+	s.Require().NotContains(cfg.DetailString(), "s3cr3t")
+
+	// a missing secret fails loudly
+	s.Require().NoError(os.Setenv(EnvCallbackSecretFile, secretFile+".missing"))
+	_, err = GetConfigFromEnv()
+	s.Require().ErrorContains(err, EnvCallbackSecretFile)
+}
+
+func (s *argsTestSuite) TestGetConfigFromEnvCallbackAuth() {
+	initialEnv := s.clearEnv()
+	defer s.setEnv(nil, initialEnv)
+	defer os.Unsetenv(EnvCallbackAuth)
+
+	absPath, err := filepath.Abs(filepath.Join("testdata", "validate"))
+	s.Require().NoError(err)
+	s.Require().NoError(os.Setenv(EnvHome, absPath))
+	s.Require().NoError(os.Setenv(EnvName, "testname"))
+
+	secretFile := filepath.Join(s.T().TempDir(), "hmac-key")
+	s.Require().NoError(os.WriteFile(secretFile, []byte("k3y\n"), 0o600))
+	s.Require().NoError(os.Setenv(EnvCallbackAuth, "heartbeat=hmac:"+secretFile))
+	cfg, err := GetConfigFromEnv()
+	s.Require().NoError(err)
+	s.Require().Equal(map[CallbackEvent]CallbackAuth{
+		CallbackEventHeartbeat: {Type: CallbackAuthHMAC, SecretRef: secretFile, secret: "k3y"},
+	}, cfg.CallbackAuth)
+	s.Require().NotContains(cfg.DetailString(), "k3y")
+
+	// a missing secret fails loudly
+	s.Require().NoError(os.Setenv(EnvCallbackAuth, "heartbeat=hmac:"+secretFile+".missing"))
+	_, err = GetConfigFromEnv()
+	s.Require().ErrorContains(err, EnvCallbackAuth)
+}
+
+func (s *argsTestSuite) TestGetConfigFromEnvBinResolveRetries() {
+	initialEnv := s.clearEnv()
+	defer s.setEnv(nil, initialEnv)
+	defer os.Unsetenv(EnvBinResolveRetries)
+
+	absPath, err := filepath.Abs(filepath.Join("testdata", "validate"))
+	s.Require().NoError(err)
+	s.Require().NoError(os.Setenv(EnvHome, absPath))
+	s.Require().NoError(os.Setenv(EnvName, "testname"))
+
+	s.Require().NoError(os.Setenv(EnvBinResolveRetries, "3"))
+	cfg, err := GetConfigFromEnv()
+	s.Require().NoError(err)
+	s.Require().Equal(3, cfg.BinResolveRetries)
+
+	s.Require().NoError(os.Setenv(EnvBinResolveRetries, "-1"))
+	_, err = GetConfigFromEnv()
+	s.Require().ErrorContains(err, EnvBinResolveRetries+" must not be negative")
+}
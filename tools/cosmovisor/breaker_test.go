@@ -0,0 +1,174 @@
+package cosmovisor
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+
+	"cosmossdk.io/log"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	require := require.New(t)
+
+	now := time.Unix(0, 0)
+	var states []breakerState
+	b := newCircuitBreaker(3, time.Minute, func(s breakerState) { states = append(states, s) })
+	b.now = func() time.Time { return now }
+
+	errBackend := errors.New("backend down")
+
+	// failures below the threshold keep the breaker closed
+	for i := 0; i < 2; i++ {
+		require.True(b.allow())
+		b.record(errBackend)
+	}
+	require.Equal(breakerClosed, b.currentState())
+
+	// a success resets the failure count
+	require.True(b.allow())
+	b.record(nil)
+	for i := 0; i < 2; i++ {
+		require.True(b.allow())
+		b.record(errBackend)
+	}
+	require.Equal(breakerClosed, b.currentState())
+
+	// the threshold opens the breaker
+	require.True(b.allow())
+	b.record(errBackend)
+	require.Equal(breakerOpen, b.currentState())
+	require.False(b.allow())
+
+	// still open during the cooldown
+	now = now.Add(59 * time.Second)
+	require.False(b.allow())
+
+	// after the cooldown a single probe is let through
+	now = now.Add(time.Second)
+	require.True(b.allow())
+	require.Equal(breakerHalfOpen, b.currentState())
+	require.False(b.allow())
+
+	// a failed probe reopens the breaker
+	b.record(errBackend)
+	require.Equal(breakerOpen, b.currentState())
+	require.False(b.allow())
+
+	// a successful probe closes it
+	now = now.Add(time.Minute)
+	require.True(b.allow())
+	b.record(nil)
+	require.Equal(breakerClosed, b.currentState())
+	require.True(b.allow())
+
+	require.Equal([]breakerState{breakerOpen, breakerHalfOpen, breakerOpen, breakerHalfOpen, breakerClosed}, states)
+}
+
+func TestCircuitBreakerDisabled(t *testing.T) {
+	b := newCircuitBreaker(0, 0, nil)
+	for i := 0; i < 10; i++ {
+		require.True(t, b.allow())
+		b.record(errors.New("backend down"))
+	}
+	require.Equal(t, breakerClosed, b.currentState())
+
+	var nilBreaker *circuitBreaker
+	require.True(t, nilBreaker.allow())
+	nilBreaker.record(nil)
+}
+
+func TestCallbackCircuitBreaker(t *testing.T) {
+	require := require.New(t)
+
+	var requests, healthy atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		requests.Add(1)
+		if healthy.Load() == 0 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer srv.Close()
+
+	m := newMetrics()
+	cfg := &Config{CallbackAPI: srv.URL, CallbackBreakerThreshold: 2, CallbackBreakerCooldown: time.Minute}
+	d := newCallbackDispatcher(cfg, log.NewNopLogger(), m)
+	now := time.Unix(0, 0)
+	d.breaker(d.endpoint(CallbackEventDetected)).now = func() time.Time { return now }
+	state := m.callbackBreakerState.WithLabelValues(breakerEndpoint(d.endpoint(CallbackEventDetected)))
+
+	require.Error(d.send(CallbackEventDetected, callbackInfo{}))
+	require.Error(d.send(CallbackEventDetected, callbackInfo{}))
+	require.Equal(float64(breakerOpen), testutil.ToFloat64(state))
+
+	// short-circuited callbacks don't reach the backend
+	require.ErrorIs(d.send(CallbackEventDetected, callbackInfo{}), errCallbackBreakerOpen)
+	require.Equal(int32(2), requests.Load())
+
+	// the backend recovers and the half-open probe closes the breaker
+	healthy.Store(1)
+	now = now.Add(time.Minute)
+	require.NoError(d.send(CallbackEventDetected, callbackInfo{}))
+	require.Equal(int32(3), requests.Load())
+	require.Equal(float64(breakerClosed), testutil.ToFloat64(state))
+}
+
+func TestCallbackCircuitBreakerPerEndpoint(t *testing.T) {
+	require := require.New(t)
+
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer failing.Close()
+	srv := newCallbackRecorder(t)
+
+	cfg := &Config{
+		CallbackAPI:              srv.URL,
+		EventEndpoints:           map[CallbackEvent]string{CallbackEventDetected: failing.URL + "/detected"},
+		CallbackBreakerThreshold: 1,
+	}
+	d := newCallbackDispatcher(cfg, log.NewNopLogger(), nil)
+
+	require.Error(d.send(CallbackEventDetected, callbackInfo{}))
+	require.ErrorIs(d.send(CallbackEventDetected, callbackInfo{}), errCallbackBreakerOpen)
+
+	// the failing endpoint does not stop the callbacks sent to the others
+	require.NoError(d.send(CallbackEventReached, callbackInfo{}))
+	require.Len(srv.received("/internal/cosmos///"+callbackPaths[CallbackEventReached]), 1)
+	require.Equal(breakerClosed, d.breaker(d.endpoint(CallbackEventReached)).currentState())
+}
+
+func TestCallbackCircuitBreakerSharedHost(t *testing.T) {
+	require := require.New(t)
+
+	var requests atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		requests.Add(1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	cfg := &Config{
+		CallbackAPI:              srv.URL,
+		EventEndpoints:           map[CallbackEvent]string{CallbackEventHookFailed: srv.URL + "/hook"},
+		CallbackBreakerThreshold: 3,
+	}
+	d := newCallbackDispatcher(cfg, log.NewNopLogger(), nil)
+
+	// the failures of all the events posted to the host count towards its breaker
+	require.Error(d.send(CallbackEventDetected, callbackInfo{}))
+	require.Error(d.send(CallbackEventReached, callbackInfo{}))
+	require.Error(d.send(CallbackEventHookFailed, callbackInfo{}))
+	require.Equal(breakerOpen, d.breaker(d.endpoint(CallbackEventDetected)).currentState())
+
+	for _, event := range []CallbackEvent{CallbackEventDetected, CallbackEventReached, CallbackEventHookFailed, CallbackEventPlanAmended} {
+		require.ErrorIs(d.send(event, callbackInfo{}), errCallbackBreakerOpen)
+	}
+	require.Equal(int32(3), requests.Load())
+}
@@ -0,0 +1,396 @@
+package cosmovisor
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	neturl "net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/net/http/httpproxy"
+
+	"cosmossdk.io/log"
+)
+
+// CallbackEvent identifies the kind of notification sent to the callback API.
+type CallbackEvent string
+
+const (
+	CallbackEventDetected         CallbackEvent = "detected"
+	CallbackEventImminent         CallbackEvent = "imminent"
+	CallbackEventReached          CallbackEvent = "reached"
+	CallbackEventValidationFailed CallbackEvent = "validation_failed"
+	CallbackEventHeartbeat        CallbackEvent = "heartbeat"
+	CallbackEventHeightOverrun    CallbackEvent = "height_overrun"
+	CallbackEventHookFailed       CallbackEvent = "hook_failed"
+	CallbackEventNotConfirmed     CallbackEvent = "confirmation_timeout"
+	CallbackEventPlanAmended      CallbackEvent = "plan_amended"
+	CallbackEventUpgradeFailed    CallbackEvent = "upgrade_failed"
+	CallbackEventWatcherStopped   CallbackEvent = "watcher_stopped"
+	CallbackEventUpgradePending   CallbackEvent = "upgrade_pending"
+	CallbackEventInfoUnreadable   CallbackEvent = "info_unreadable"
+	CallbackEventRollbackDetected CallbackEvent = "rollback_detected"
+	CallbackEventMilestone        CallbackEvent = "milestone"
+	CallbackEventUpgradeHeld      CallbackEvent = "upgrade_held"
+	CallbackEventQuarantined      CallbackEvent = "quarantined"
+	CallbackEventDiskSpaceLow     CallbackEvent = "disk_space_low"
+	CallbackEventCosmovisorTooOld CallbackEvent = "cosmovisor_upgrade_required"
+)
+
+// callbackPaths are the paths, relative to the base callback URL, each event is posted to
+// when no endpoint override is configured for it.
+var callbackPaths = map[CallbackEvent]string{
+	CallbackEventDetected:         "cosmos_notify_upgrade",
+	CallbackEventImminent:         "cosmos_upgrade_imminent",
+	CallbackEventReached:          "cosmos_upgrade_height_reached",
+	CallbackEventValidationFailed: "cosmos_upgrade_validation_failed",
+	CallbackEventHeartbeat:        "cosmos_heartbeat",
+	CallbackEventHeightOverrun:    "cosmos_upgrade_height_overrun",
+	CallbackEventHookFailed:       "cosmos_upgrade_hook_failed",
+	CallbackEventNotConfirmed:     "cosmos_upgrade_confirmation_timeout",
+	CallbackEventPlanAmended:      "cosmos_upgrade_plan_amended",
+	CallbackEventUpgradeFailed:    "cosmos_upgrade_failed",
+	CallbackEventWatcherStopped:   "cosmos_watcher_stopped",
+	CallbackEventUpgradePending:   "cosmos_upgrade_pending",
+	CallbackEventInfoUnreadable:   "cosmos_upgrade_info_unreadable",
+	CallbackEventRollbackDetected: "cosmos_upgrade_rollback_detected",
+	CallbackEventMilestone:        "cosmos_upgrade_milestone",
+	CallbackEventUpgradeHeld:      "cosmos_upgrade_held",
+	CallbackEventQuarantined:      "cosmos_upgrade_info_quarantined",
+	CallbackEventDiskSpaceLow:     "cosmos_upgrade_disk_space_low",
+	CallbackEventCosmovisorTooOld: "cosmos_cosmovisor_upgrade_required",
+}
+
+type callbackInfo struct {
+	Event   CallbackEvent `json:"event"`
+	Name    string        `json:"name"`
+	Version string        `json:"version"`
+	Repo    string        `json:"repo"`
+	Info    string        `json:"info"`
+	Height  int64         `json:"height"`
+	Error   string        `json:"error,omitempty"`
+
+	// CurrentHeight is the block height of the node, for events where it is relevant.
+	CurrentHeight int64 `json:"current_height,omitempty"`
+
+	// BlocksRemaining and ETASeconds are the progress towards the upgrade height of an
+	// upgrade_pending event, BlocksRemaining that of an imminent event too. ETASeconds is 0 while
+	// the block rate is unknown.
+	BlocksRemaining int64 `json:"blocks_remaining,omitempty"`
+	ETASeconds      int64 `json:"eta_seconds,omitempty"`
+
+	// Reason is the reason code of an upgrade_pending event, the same as logged.
+	Reason string `json:"reason,omitempty"`
+
+	// PreviousName and PreviousHeight are the upgrade tracked before a rollback_detected event.
+	PreviousName   string `json:"previous_name,omitempty"`
+	PreviousHeight int64  `json:"previous_height,omitempty"`
+
+	// Milestone is the offset from the upgrade height of a milestone event.
+	Milestone int64 `json:"milestone,omitempty"`
+
+	// QuarantinePath is where the invalid upgrade info file of a quarantined event was moved to.
+	QuarantinePath string `json:"quarantine_path,omitempty"`
+
+	// FreeDiskBytes is the lowest free space of the directories checked by a disk_space_low event.
+	FreeDiskBytes uint64 `json:"free_disk_bytes,omitempty"`
+
+	// RequiredCosmovisorVersion and CosmovisorVersion are the minimum version required by the plan
+	// and the version of this cosmovisor, in a cosmovisor_upgrade_required event.
+	RequiredCosmovisorVersion string `json:"required_cosmovisor_version,omitempty"`
+	CosmovisorVersion         string `json:"cosmovisor_version,omitempty"`
+
+	// Tags are the operator tags from Config.CallbackTags. They are nested so they can never
+	// shadow one of the fields above.
+	Tags map[string]string `json:"tags,omitempty"`
+}
+
+// errCallbackBreakerOpen is returned for callbacks dropped while the circuit breaker is open.
+var errCallbackBreakerOpen = errors.New("callback circuit breaker is open")
+
+// callbackDispatcher delivers upgrade notifications to the callback API.
+type callbackDispatcher struct {
+	logger    log.Logger
+	client    *http.Client
+	baseURL   string
+	endpoints map[CallbackEvent]string
+	tags      map[string]string
+	dryRun    bool
+	metrics   *metrics
+	secret    string
+	auth      map[CallbackEvent]CallbackAuth
+	journal   *journalSink
+
+	// breakers holds the circuit breaker of each callback host, created by newBreaker on first use
+	breakersMu sync.Mutex
+	breakers   map[string]*circuitBreaker
+	newBreaker func(endpoint string) *circuitBreaker
+}
+
+func newCallbackDispatcher(cfg *Config, logger log.Logger, m *metrics) *callbackDispatcher {
+	newBreaker := func(endpoint string) *circuitBreaker {
+		return newCircuitBreaker(cfg.CallbackBreakerThreshold, cfg.CallbackBreakerCooldown, func(state breakerState) {
+			logger.Info("callback circuit breaker changed state", "endpoint", endpoint, "state", state)
+			m.setBreakerState(endpoint, state)
+		})
+	}
+
+	return &callbackDispatcher{
+		logger:     logger,
+		client:     newCallbackClient(cfg),
+		baseURL:    cfg.CallbackBaseURL(),
+		endpoints:  cfg.EventEndpoints,
+		tags:       cfg.CallbackTags,
+		breakers:   make(map[string]*circuitBreaker),
+		newBreaker: newBreaker,
+		dryRun:     cfg.CallbackDryRun,
+		secret:     cfg.callbackSecret,
+		auth:       cfg.CallbackAuth,
+		journal:    newJournalSink(cfg, logger),
+		metrics:    m,
+	}
+}
+
+// newCallbackClient returns the HTTP client callbacks are sent with. Requests go through
+// Config.HTTPProxy if set, except for the hosts of Config.NoProxy; otherwise the proxy
+// environment variables apply.
+func newCallbackClient(cfg *Config) *http.Client {
+	if cfg.HTTPProxy == "" {
+		return http.DefaultClient
+	}
+
+	proxy := (&httpproxy.Config{
+		HTTPProxy:  cfg.HTTPProxy,
+		HTTPSProxy: cfg.HTTPProxy,
+		NoProxy:    cfg.NoProxy,
+	}).ProxyFunc()
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = func(req *http.Request) (*neturl.URL, error) {
+		return proxy(req.URL)
+	}
+
+	return &http.Client{Transport: transport}
+}
+
+// endpoint returns the URL the given event is delivered to.
+// An override in Config.EventEndpoints takes precedence over the base callback URL.
+// An empty string is returned when no callback API is configured.
+func (d *callbackDispatcher) endpoint(event CallbackEvent) string {
+	if url := d.endpoints[event]; url != "" {
+		return url
+	}
+
+	if d.baseURL == "" {
+		return ""
+	}
+
+	return d.baseURL + "/" + callbackPaths[event]
+}
+
+// send posts the callback info for the given event to its endpoint.
+func (d *callbackDispatcher) send(event CallbackEvent, info callbackInfo) error {
+	return d.sendContext(context.Background(), event, info)
+}
+
+// sendTimeout is like send but gives up once the timeout elapses.
+func (d *callbackDispatcher) sendTimeout(event CallbackEvent, info callbackInfo, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	return d.sendContext(ctx, event, info)
+}
+
+func (d *callbackDispatcher) sendContext(ctx context.Context, event CallbackEvent, info callbackInfo) error {
+	info.Event = event
+	info.Tags = d.tags
+	// the journal gets every event, whether or not it has an endpoint
+	d.journal.send(event, info)
+
+	url := d.endpoint(event)
+	if url == "" {
+		return nil
+	}
+
+	bz, err := json.Marshal(info)
+	if err != nil {
+		return fmt.Errorf("failed to marshal %s callback: %w", event, err)
+	}
+
+	if d.dryRun {
+		req, err := d.newRequest(ctx, event, url, bz)
+		if err != nil {
+			return err
+		}
+		headers := req.Header.Clone()
+		if headers.Get("Authorization") != "" {
+			headers.Set("Authorization", "[redacted]")
+		}
+		d.logger.Info("dry run, not sending upgrade callback", "event", event, "url", url, "headers", headers, "payload", string(bz))
+		return nil
+	}
+
+	breaker := d.breaker(url)
+	if !breaker.allow() {
+		// the callback is lost, it is not replayed once the breaker closes
+		d.logger.Error("callback circuit breaker is open, dropping callback", "event", event, "url", url)
+		return errCallbackBreakerOpen
+	}
+
+	d.logger.Info("sending upgrade callback", "event", event, "url", url)
+	d.metrics.addInflight(1)
+	start := time.Now()
+	err = d.post(ctx, event, url, bz)
+	d.metrics.addInflight(-1)
+	d.metrics.observeCallback(event, url, time.Since(start), err)
+	breaker.record(err)
+	if err != nil {
+		d.logger.Error("upgrade callback failed", "event", event, "url", url, "error", err)
+	}
+
+	return err
+}
+
+// breaker returns the circuit breaker of the host of url, so that a failing backend does not stop
+// the callbacks sent to the others. The events posted under the same host share its breaker,
+// whatever their path.
+func (d *callbackDispatcher) breaker(url string) *circuitBreaker {
+	endpoint := breakerEndpoint(url)
+
+	d.breakersMu.Lock()
+	defer d.breakersMu.Unlock()
+
+	b, ok := d.breakers[endpoint]
+	if !ok {
+		b = d.newBreaker(endpoint)
+		d.breakers[endpoint] = b
+	}
+
+	return b
+}
+
+// breakerEndpoint is the scheme and host of a callback URL, which its circuit breaker is keyed on.
+func breakerEndpoint(rawURL string) string {
+	u, err := neturl.Parse(rawURL)
+	if err != nil {
+		return "invalid"
+	}
+
+	return (&neturl.URL{Scheme: u.Scheme, Host: u.Host}).String()
+}
+
+// newRequest builds the request posting the payload of the event to url. It is authenticated
+// as configured for the event, or else with the callback secret, if any.
+func (d *callbackDispatcher) newRequest(ctx context.Context, event CallbackEvent, url string, payload []byte) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewBuffer(payload))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if auth, ok := d.auth[event]; ok {
+		auth.apply(req, payload)
+	} else if d.secret != "" {
+		req.Header.Set("Authorization", "Bearer "+d.secret)
+	}
+
+	return req, nil
+}
+
+// post sends the payload of the event to url and checks that the response status is 2xx.
+func (d *callbackDispatcher) post(ctx context.Context, event CallbackEvent, url string, payload []byte) error {
+	req, err := d.newRequest(ctx, event, url, payload)
+	if err != nil {
+		return err
+	}
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("callback %s returned status %s", url, resp.Status)
+	}
+
+	return nil
+}
+
+// parseCallbackEndpoints parses a comma separated list of event=url pairs.
+func parseCallbackEndpoints(input string) (map[CallbackEvent]string, error) {
+	pairs, err := parseEnvMap(input)
+	if err != nil {
+		return nil, err
+	}
+
+	endpoints := make(map[CallbackEvent]string, len(pairs))
+	for event, url := range pairs {
+		if _, ok := callbackPaths[CallbackEvent(event)]; !ok {
+			return nil, fmt.Errorf("unknown callback event %q", event)
+		}
+		endpoints[CallbackEvent(event)] = url
+	}
+
+	return endpoints, nil
+}
+
+// formatCallbackEndpoints is the inverse of parseCallbackEndpoints.
+func formatCallbackEndpoints(endpoints map[CallbackEvent]string) string {
+	m := make(map[string]string, len(endpoints))
+	for event, url := range endpoints {
+		m[string(event)] = url
+	}
+
+	return formatEnvMap(m)
+}
+
+// formatEnvMap is the inverse of parseEnvMap. Pairs are sorted by key.
+func formatEnvMap(m map[string]string) string {
+	pairs := make([]string, 0, len(m))
+	for key, value := range m {
+		pairs = append(pairs, key+"="+value)
+	}
+	sort.Strings(pairs)
+
+	return strings.Join(pairs, ",")
+}
+
+// parseEnvList parses a comma separated list, ignoring empty items.
+func parseEnvList(input string) []string {
+	var items []string
+	for _, item := range strings.Split(input, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+
+	return items
+}
+
+// parseEnvMap parses a comma separated list of key=value pairs.
+func parseEnvMap(input string) (map[string]string, error) {
+	m := make(map[string]string)
+	for _, pair := range strings.Split(input, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+
+		key, value, ok := strings.Cut(pair, "=")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" {
+			return nil, fmt.Errorf("invalid key=value pair %q", pair)
+		}
+		m[key] = strings.TrimSpace(value)
+	}
+
+	return m, nil
+}
@@ -0,0 +1,391 @@
+package cosmovisor
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+
+	"cosmossdk.io/log"
+)
+
+// callbackRecorder is a test server recording the path and payload of every received callback.
+type callbackRecorder struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	requests map[string][]callbackInfo
+}
+
+func newCallbackRecorder(t *testing.T) *callbackRecorder {
+	t.Helper()
+
+	r := &callbackRecorder{requests: make(map[string][]callbackInfo)}
+	r.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		bz, err := io.ReadAll(req.Body)
+		require.NoError(t, err)
+
+		var info callbackInfo
+		require.NoError(t, json.Unmarshal(bz, &info))
+
+		r.mu.Lock()
+		r.requests[req.URL.Path] = append(r.requests[req.URL.Path], info)
+		r.mu.Unlock()
+	}))
+	t.Cleanup(r.Close)
+
+	return r
+}
+
+func (r *callbackRecorder) received(path string) []callbackInfo {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.requests[path]
+}
+
+func TestCallbackEventEndpoints(t *testing.T) {
+	srv := newCallbackRecorder(t)
+	cfg := &Config{
+		CallbackAPI:  srv.URL,
+		NodeID:       "node",
+		DeploymentID: "deployment",
+		EventEndpoints: map[CallbackEvent]string{
+			CallbackEventDetected:         srv.URL + "/detected",
+			CallbackEventImminent:         srv.URL + "/imminent",
+			CallbackEventReached:          srv.URL + "/alerts/reached",
+			CallbackEventValidationFailed: srv.URL + "/alerts/invalid",
+			CallbackEventHeartbeat:        srv.URL + "/metrics/heartbeat",
+		},
+	}
+	d := newCallbackDispatcher(cfg, log.NewNopLogger(), nil)
+
+	for event, url := range cfg.EventEndpoints {
+		require.Equal(t, url, d.endpoint(event))
+		require.NoError(t, d.send(event, callbackInfo{Name: "chain2", Height: 49}))
+	}
+
+	for event, url := range cfg.EventEndpoints {
+		got := srv.received(url[len(srv.URL):])
+		require.Len(t, got, 1, event)
+		require.Equal(t, event, got[0].Event)
+		require.Equal(t, "chain2", got[0].Name)
+		require.Equal(t, int64(49), got[0].Height)
+	}
+}
+
+func TestCallbackEventEndpointsFallback(t *testing.T) {
+	srv := newCallbackRecorder(t)
+	cfg := &Config{
+		CallbackAPI:  srv.URL,
+		NodeID:       "node",
+		DeploymentID: "deployment",
+		EventEndpoints: map[CallbackEvent]string{
+			CallbackEventHeartbeat: srv.URL + "/metrics/heartbeat",
+		},
+	}
+	d := newCallbackDispatcher(cfg, log.NewNopLogger(), nil)
+
+	require.NoError(t, d.send(CallbackEventHeartbeat, callbackInfo{}))
+	require.NoError(t, d.send(CallbackEventDetected, callbackInfo{Name: "chain2"}))
+	require.NoError(t, d.send(CallbackEventReached, callbackInfo{Name: "chain2"}))
+
+	require.Len(t, srv.received("/metrics/heartbeat"), 1)
+	require.Len(t, srv.received("/internal/cosmos/node/deployment/cosmos_notify_upgrade"), 1)
+	require.Len(t, srv.received("/internal/cosmos/node/deployment/cosmos_upgrade_height_reached"), 1)
+}
+
+func TestCallbackNotConfigured(t *testing.T) {
+	d := newCallbackDispatcher(&Config{}, log.NewNopLogger(), nil)
+	require.Equal(t, "", d.endpoint(CallbackEventDetected))
+	require.NoError(t, d.send(CallbackEventDetected, callbackInfo{Name: "chain2"}))
+}
+
+func TestParseCallbackEndpoints(t *testing.T) {
+	cases := map[string]struct {
+		input     string
+		expect    map[CallbackEvent]string
+		expectErr bool
+	}{
+		"empty": {
+			input:  "",
+			expect: map[CallbackEvent]string{},
+		},
+		"multiple": {
+			input: "reached=https://alerts.example.com/hook, heartbeat=https://metrics.example.com/hb",
+			expect: map[CallbackEvent]string{
+				CallbackEventReached:   "https://alerts.example.com/hook",
+				CallbackEventHeartbeat: "https://metrics.example.com/hb",
+			},
+		},
+		"unknown event": {
+			input:     "finished=https://example.com",
+			expectErr: true,
+		},
+		"missing separator": {
+			input:     "reached",
+			expectErr: true,
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			endpoints, err := parseCallbackEndpoints(tc.input)
+			if tc.expectErr {
+				require.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, tc.expect, endpoints)
+		})
+	}
+}
+
+func TestCallbackTags(t *testing.T) {
+	srv := newCallbackRecorder(t)
+	cfg := &Config{
+		CallbackAPI:  srv.URL,
+		NodeID:       "node",
+		DeploymentID: "deployment",
+		CallbackTags: map[string]string{
+			"datacenter": "fra1",
+			"role":       "validator",
+			// reserved field names stay nested under tags
+			"name":   "spoofed",
+			"height": "1",
+		},
+	}
+	d := newCallbackDispatcher(cfg, log.NewNopLogger(), nil)
+
+	for event := range callbackPaths {
+		require.NoError(t, d.send(event, callbackInfo{Name: "chain2", Height: 49}))
+
+		got := srv.received("/internal/cosmos/node/deployment/" + callbackPaths[event])
+		require.Len(t, got, 1, event)
+		require.Equal(t, cfg.CallbackTags, got[0].Tags)
+		require.Equal(t, "chain2", got[0].Name)
+		require.Equal(t, int64(49), got[0].Height)
+		require.Equal(t, event, got[0].Event)
+	}
+}
+
+func TestCallbackTagsMarshal(t *testing.T) {
+	bz, err := json.Marshal(callbackInfo{
+		Event:  CallbackEventReached,
+		Name:   "chain2",
+		Height: 49,
+		Tags:   map[string]string{"cluster": "eu", "name": "spoofed"},
+	})
+	require.NoError(t, err)
+
+	var raw map[string]json.RawMessage
+	require.NoError(t, json.Unmarshal(bz, &raw))
+	require.JSONEq(t, `"chain2"`, string(raw["name"]))
+	require.JSONEq(t, `49`, string(raw["height"]))
+	require.JSONEq(t, `{"cluster":"eu","name":"spoofed"}`, string(raw["tags"]))
+
+	// tags are omitted when none are configured
+	bz, err = json.Marshal(callbackInfo{Name: "chain2"})
+	require.NoError(t, err)
+	require.NotContains(t, string(bz), "tags")
+}
+
+func TestCallbackHTTPProxy(t *testing.T) {
+	var mu sync.Mutex
+	var proxied []string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		// a proxied request carries the absolute URL of the target
+		mu.Lock()
+		proxied = append(proxied, req.URL.String())
+		mu.Unlock()
+	}))
+	defer proxy.Close()
+
+	cfg := &Config{
+		CallbackAPI:  "http://callbacks.example",
+		NodeID:       "node",
+		DeploymentID: "deployment",
+		EventEndpoints: map[CallbackEvent]string{
+			CallbackEventHeartbeat: "http://direct.example/heartbeat",
+		},
+		HTTPProxy: proxy.URL,
+		NoProxy:   "direct.example",
+	}
+	d := newCallbackDispatcher(cfg, log.NewNopLogger(), nil)
+
+	require.NoError(t, d.send(CallbackEventDetected, callbackInfo{Name: "chain2"}))
+	require.Equal(t, []string{"http://callbacks.example/internal/cosmos/node/deployment/cosmos_notify_upgrade"}, proxied)
+
+	// hosts of the no-proxy list are reached directly, which fails for this unresolvable host
+	require.Error(t, d.send(CallbackEventHeartbeat, callbackInfo{}))
+	require.Len(t, proxied, 1)
+}
+
+func TestCallbackClientDefault(t *testing.T) {
+	require.Same(t, http.DefaultClient, newCallbackClient(&Config{}))
+}
+
+func TestCallbackDryRun(t *testing.T) {
+	var requests atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		requests.Add(1)
+	}))
+	defer srv.Close()
+
+	var buf bytes.Buffer
+	cfg := &Config{
+		CallbackAPI:    srv.URL,
+		NodeID:         "node",
+		DeploymentID:   "deployment",
+		CallbackDryRun: true,
+		LogFormat:      LogFormatJSON,
+	}
+	d := newCallbackDispatcher(cfg, cfg.Logger(&buf), nil)
+
+	for event := range callbackPaths {
+		buf.Reset()
+		require.NoError(t, d.send(event, callbackInfo{Name: "chain2", Height: 49}))
+
+		var entry struct {
+			Message string              `json:"message"`
+			URL     string              `json:"url"`
+			Headers map[string][]string `json:"headers"`
+			Payload string              `json:"payload"`
+		}
+		require.NoError(t, json.Unmarshal(buf.Bytes(), &entry), buf.String())
+		require.Equal(t, "dry run, not sending upgrade callback", entry.Message)
+		require.Equal(t, srv.URL+"/internal/cosmos/node/deployment/"+callbackPaths[event], entry.URL)
+		require.Equal(t, []string{"application/json"}, entry.Headers["Content-Type"])
+
+		var info callbackInfo
+		require.NoError(t, json.Unmarshal([]byte(entry.Payload), &info))
+		require.Equal(t, callbackInfo{Event: event, Name: "chain2", Height: 49}, info)
+	}
+
+	require.Zero(t, requests.Load())
+}
+
+func TestCallbackInflightMetric(t *testing.T) {
+	require := require.New(t)
+
+	var current atomic.Int32
+	unblock := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		current.Add(1)
+		defer current.Add(-1)
+		<-unblock
+	}))
+	defer srv.Close()
+
+	m := newMetrics()
+	d := newCallbackDispatcher(&Config{CallbackAPI: srv.URL}, log.NewNopLogger(), m)
+
+	events := []CallbackEvent{CallbackEventDetected, CallbackEventImminent, CallbackEventReached, CallbackEventHeartbeat}
+	var wg sync.WaitGroup
+	errs := make(chan error, len(events))
+	for _, event := range events {
+		wg.Add(1)
+		go func(event CallbackEvent) {
+			defer wg.Done()
+			errs <- d.send(event, callbackInfo{Name: "chain2"})
+		}(event)
+	}
+
+	// the slow backend holds all the callbacks
+	require.Eventually(func() bool { return current.Load() == int32(len(events)) }, 5*time.Second, time.Millisecond)
+	require.Equal(float64(len(events)), testutil.ToFloat64(m.callbackInflight))
+
+	close(unblock)
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		require.NoError(err)
+	}
+	require.Zero(testutil.ToFloat64(m.callbackInflight))
+}
+
+func TestCallbackSecret(t *testing.T) {
+	var auth []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		auth = append(auth, req.Header.Get("Authorization"))
+	}))
+	defer srv.Close()
+
+	var buf bytes.Buffer
+	cfg := &Config{CallbackAPI: srv.URL, callbackSecret: "s3cr3t", LogFormat: LogFormatJSON}
+	d := newCallbackDispatcher(cfg, cfg.Logger(&buf), nil)
+	require.NoError(t, d.send(CallbackEventDetected, callbackInfo{Name: "chain2"}))
+	require.Equal(t, []string{"Bearer s3cr3t"}, auth)
+
+	// the secret is not logged in dry-run mode
+	d.dryRun = true
+	buf.Reset()
+	require.NoError(t, d.send(CallbackEventDetected, callbackInfo{Name: "chain2"}))
+	require.Contains(t, buf.String(), "[redacted]")
+	require.NotContains(t, buf.String(), "s3cr3t")
+	require.Len(t, auth, 1)
+}
+
+func TestCallbackLatencyMetrics(t *testing.T) {
+	require := require.New(t)
+
+	ok := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {}))
+	defer ok.Close()
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer failing.Close()
+
+	m := newMetrics()
+	cfg := &Config{
+		CallbackAPI:    ok.URL,
+		EventEndpoints: map[CallbackEvent]string{CallbackEventReached: failing.URL + "/reached?token=s3cr3t"},
+	}
+	d := newCallbackDispatcher(cfg, log.NewNopLogger(), m)
+	require.NoError(d.send(CallbackEventDetected, callbackInfo{Name: "chain2"}))
+	require.NoError(d.send(CallbackEventDetected, callbackInfo{Name: "chain2"}))
+	require.Error(d.send(CallbackEventReached, callbackInfo{Name: "chain2"}))
+
+	// observations are recorded per event and endpoint, successful or not
+	samples := make(map[string]uint64)
+	families, err := m.registry.Gather()
+	require.NoError(err)
+	for _, family := range families {
+		if family.GetName() != "cosmovisor_callback_duration_seconds" {
+			continue
+		}
+		for _, metric := range family.GetMetric() {
+			labels := make(map[string]string)
+			for _, label := range metric.GetLabel() {
+				labels[label.GetName()] = label.GetValue()
+			}
+			samples[labels["event"]+" "+labels["endpoint"]] = metric.GetHistogram().GetSampleCount()
+		}
+	}
+	require.Equal(map[string]uint64{
+		"detected " + d.endpoint(CallbackEventDetected): 2,
+		"reached " + failing.URL + "/reached":           1,
+	}, samples)
+
+	// only the final failures are counted
+	require.Equal(1, testutil.CollectAndCount(m.callbackFailures))
+	require.Equal(float64(1), testutil.ToFloat64(m.callbackFailures.WithLabelValues("reached", failing.URL+"/reached")))
+}
+
+func TestCallbackLatencyMetricsDisabled(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {}))
+	defer srv.Close()
+
+	// without the metrics server the instrumentation is a no-op
+	d := newCallbackDispatcher(&Config{CallbackAPI: srv.URL}, log.NewNopLogger(), nil)
+	require.NoError(t, d.send(CallbackEventDetected, callbackInfo{}))
+}
@@ -0,0 +1,105 @@
+package cosmovisor
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// callback authentication schemes
+const (
+	// CallbackAuthBearer sends the secret as an Authorization: Bearer token
+	CallbackAuthBearer = "bearer"
+	// CallbackAuthBasic sends the user:password secret as Authorization: Basic credentials
+	CallbackAuthBasic = "basic"
+	// CallbackAuthHMAC signs the payload with the secret, see callbackSignatureHeader
+	CallbackAuthHMAC = "hmac"
+)
+
+// callbackSignatureHeader carries the hex encoded HMAC-SHA256 of the payload, prefixed with
+// sha256=, of the callbacks authenticated with CallbackAuthHMAC.
+const callbackSignatureHeader = "X-Cosmovisor-Signature"
+
+// CallbackAuth is how the callbacks of an event are authenticated.
+type CallbackAuth struct {
+	Type string
+	// SecretRef references the secret, a token, a user:password pair or an HMAC key depending
+	// on Type, in the forms supported by COSMOVISOR_CALLBACK_SECRET_FILE.
+	SecretRef string
+
+	// secret is the secret loaded from SecretRef
+	secret string
+}
+
+// load returns the auth with its secret loaded.
+func (a CallbackAuth) load() (CallbackAuth, error) {
+	secret, err := loadSecret(a.SecretRef)
+	if err != nil {
+		return a, err
+	}
+	if a.Type == CallbackAuthBasic && !strings.Contains(secret, ":") {
+		return a, fmt.Errorf("basic auth secret %s must be a user:password pair", a.SecretRef)
+	}
+	a.secret = secret
+
+	return a, nil
+}
+
+// apply authenticates the request posting payload.
+func (a CallbackAuth) apply(req *http.Request, payload []byte) {
+	switch a.Type {
+	case CallbackAuthBearer:
+		req.Header.Set("Authorization", "Bearer "+a.secret)
+	case CallbackAuthBasic:
+		user, password, _ := strings.Cut(a.secret, ":")
+		req.SetBasicAuth(user, password)
+	case CallbackAuthHMAC:
+		mac := hmac.New(sha256.New, []byte(a.secret))
+		mac.Write(payload)
+		req.Header.Set(callbackSignatureHeader, "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	}
+}
+
+// parseCallbackAuth parses a comma separated list of event=type:secret-ref pairs, e.g.
+// reached=bearer:env://TOKEN. The secrets are not loaded.
+func parseCallbackAuth(input string) (map[CallbackEvent]CallbackAuth, error) {
+	pairs, err := parseEnvMap(input)
+	if err != nil {
+		return nil, err
+	}
+
+	auth := make(map[CallbackEvent]CallbackAuth, len(pairs))
+	for event, spec := range pairs {
+		if _, ok := callbackPaths[CallbackEvent(event)]; !ok {
+			return nil, fmt.Errorf("unknown callback event %q", event)
+		}
+
+		authType, ref, _ := strings.Cut(spec, ":")
+		switch authType {
+		case CallbackAuthBearer, CallbackAuthBasic, CallbackAuthHMAC:
+		default:
+			return nil, fmt.Errorf("unknown auth type %q for %s, expected %s, %s or %s", authType, event, CallbackAuthBearer, CallbackAuthBasic, CallbackAuthHMAC)
+		}
+		if ref == "" {
+			return nil, fmt.Errorf("missing secret reference for %s", event)
+		}
+		auth[CallbackEvent(event)] = CallbackAuth{Type: authType, SecretRef: ref}
+	}
+
+	return auth, nil
+}
+
+// formatCallbackAuth is the inverse of parseCallbackAuth, never showing the secrets.
+func formatCallbackAuth(auth map[CallbackEvent]CallbackAuth) string {
+	pairs := make([]string, 0, len(auth))
+	for event, a := range auth {
+		pairs = append(pairs, fmt.Sprintf("%s=%s:%s", event, a.Type, a.SecretRef))
+	}
+	sort.Strings(pairs)
+
+	return strings.Join(pairs, ",")
+}
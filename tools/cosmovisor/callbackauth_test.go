@@ -0,0 +1,102 @@
+package cosmovisor
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"cosmossdk.io/log"
+)
+
+func TestCallbackAuthPerEndpoint(t *testing.T) {
+	var mu sync.Mutex
+	headers := make(map[string]http.Header)
+	bodies := make(map[string][]byte)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		bz, err := io.ReadAll(req.Body)
+		require.NoError(t, err)
+
+		mu.Lock()
+		defer mu.Unlock()
+		headers[req.URL.Path] = req.Header
+		bodies[req.URL.Path] = bz
+	}))
+	defer srv.Close()
+
+	cfg := &Config{
+		CallbackAPI: srv.URL,
+		EventEndpoints: map[CallbackEvent]string{
+			CallbackEventDetected:  srv.URL + "/bearer",
+			CallbackEventReached:   srv.URL + "/basic",
+			CallbackEventHeartbeat: srv.URL + "/hmac",
+		},
+		CallbackAuth: map[CallbackEvent]CallbackAuth{
+			CallbackEventDetected:  {Type: CallbackAuthBearer, secret: "t0ken"},
+			CallbackEventReached:   {Type: CallbackAuthBasic, secret: "user:pa:ss"},
+			CallbackEventHeartbeat: {Type: CallbackAuthHMAC, secret: "k3y"},
+		},
+		callbackSecret: "s3cr3t",
+	}
+	d := newCallbackDispatcher(cfg, log.NewNopLogger(), nil)
+	for _, event := range []CallbackEvent{CallbackEventDetected, CallbackEventReached, CallbackEventHeartbeat, CallbackEventImminent} {
+		require.NoError(t, d.send(event, callbackInfo{Name: "chain2", Height: 49}))
+	}
+
+	require.Equal(t, "Bearer t0ken", headers["/bearer"].Get("Authorization"))
+
+	user, password, ok := (&http.Request{Header: headers["/basic"]}).BasicAuth()
+	require.True(t, ok)
+	require.Equal(t, "user", user)
+	require.Equal(t, "pa:ss", password)
+
+	// the signature covers the exact payload received
+	mac := hmac.New(sha256.New, []byte("k3y"))
+	mac.Write(bodies["/hmac"])
+	require.Equal(t, "sha256="+hex.EncodeToString(mac.Sum(nil)), headers["/hmac"].Get(callbackSignatureHeader))
+	require.Empty(t, headers["/hmac"].Get("Authorization"))
+
+	// events without their own auth keep using the callback secret
+	imminent := headers[strings.TrimPrefix(d.endpoint(CallbackEventImminent), srv.URL)]
+	require.Equal(t, "Bearer s3cr3t", imminent.Get("Authorization"))
+	require.Empty(t, imminent.Get(callbackSignatureHeader))
+}
+
+func TestParseCallbackAuth(t *testing.T) {
+	auth, err := parseCallbackAuth("reached=bearer:env://TOKEN, heartbeat=hmac:/etc/hmac-key,detected=basic:file:///etc/basic")
+	require.NoError(t, err)
+	require.Equal(t, map[CallbackEvent]CallbackAuth{
+		CallbackEventReached:   {Type: CallbackAuthBearer, SecretRef: "env://TOKEN"},
+		CallbackEventHeartbeat: {Type: CallbackAuthHMAC, SecretRef: "/etc/hmac-key"},
+		CallbackEventDetected:  {Type: CallbackAuthBasic, SecretRef: "file:///etc/basic"},
+	}, auth)
+	require.Equal(t, "detected=basic:file:///etc/basic,heartbeat=hmac:/etc/hmac-key,reached=bearer:env://TOKEN", formatCallbackAuth(auth))
+
+	for _, input := range []string{"unknown=bearer:env://TOKEN", "reached=digest:env://TOKEN", "reached=bearer", "reached=bearer:"} {
+		_, err := parseCallbackAuth(input)
+		require.Error(t, err, input)
+	}
+}
+
+func TestCallbackAuthLoad(t *testing.T) {
+	t.Setenv("COSMOVISOR_TEST_BASIC", "user:password")
+	t.Setenv("COSMOVISOR_TEST_TOKEN", "t0ken")
+
+	auth, err := CallbackAuth{Type: CallbackAuthBasic, SecretRef: "env://COSMOVISOR_TEST_BASIC"}.load()
+	require.NoError(t, err)
+	require.Equal(t, "user:password", auth.secret)
+
+	// basic auth needs a user and a password
+	_, err = CallbackAuth{Type: CallbackAuthBasic, SecretRef: "env://COSMOVISOR_TEST_TOKEN"}.load()
+	require.ErrorContains(t, err, "user:password")
+
+	_, err = CallbackAuth{Type: CallbackAuthBearer, SecretRef: "env://COSMOVISOR_TEST_MISSING"}.load()
+	require.Error(t, err)
+}
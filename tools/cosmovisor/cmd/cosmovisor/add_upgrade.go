@@ -6,7 +6,6 @@ import (
 	"os"
 	"path"
 	"path/filepath"
-	"strings"
 
 	"github.com/spf13/cobra"
 
@@ -40,7 +39,7 @@ func AddUpgrade(cmd *cobra.Command, args []string) error {
 
 	upgradeName := args[0]
 	if !cfg.DisableRecase {
-		upgradeName = strings.ToLower(args[0])
+		upgradeName = cosmovisor.RecaseUpgradeName(args[0], cfg.RecaseExceptions)
 	}
 
 	executablePath := args[1]
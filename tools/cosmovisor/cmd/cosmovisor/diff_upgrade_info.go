@@ -0,0 +1,98 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+
+	"github.com/upnodedev/cosmos-sdk/tools/cosmovisor"
+)
+
+func NewDiffUpgradeInfoCmd() *cobra.Command {
+	diffUpgradeInfo := &cobra.Command{
+		Use:          "diff-upgrade-info [old upgrade-info.json] [new upgrade-info.json]",
+		Short:        "Show what changed between two upgrade-info.json files, e.g. the active and a proposed one.",
+		SilenceUsage: true,
+		Args:         cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := cosmovisor.GetConfigFromEnv()
+			if err != nil {
+				return err
+			}
+
+			diff, err := cosmovisor.DiffUpgradeInfo(cfg, args[0], args[1])
+			if err != nil {
+				return err
+			}
+
+			noColor, err := cmd.Flags().GetBool(cosmovisor.FlagNoColor)
+			if err != nil {
+				return err
+			}
+
+			cmd.Print(formatUpgradeInfoDiff(args[0], args[1], diff, !noColor && !color.NoColor))
+			return nil
+		},
+	}
+
+	diffUpgradeInfo.Flags().Bool(cosmovisor.FlagNoColor, false, "do not colorize the output")
+
+	return diffUpgradeInfo
+}
+
+func formatUpgradeInfoDiff(oldPath, newPath string, d cosmovisor.UpgradeInfoDiff, colorize bool) string {
+	paint := func(attrs ...color.Attribute) func(format string, a ...interface{}) string {
+		c := color.New(attrs...)
+		if colorize {
+			c.EnableColor()
+		} else {
+			c.DisableColor()
+		}
+		return c.Sprintf
+	}
+	removed, added, changed, significant := paint(color.FgRed), paint(color.FgGreen), paint(color.FgYellow), paint(color.FgRed, color.Bold)
+
+	var sb strings.Builder
+	sb.WriteString(removed("--- %s\n", oldPath))
+	sb.WriteString(added("+++ %s\n", newPath))
+	if d.Empty() {
+		sb.WriteString("No differences.\n")
+		return sb.String()
+	}
+
+	for _, f := range d.Fields {
+		sb.WriteString(changed("~ %s: %s -> %s\n", f.Field, f.Old, f.New))
+	}
+
+	if d.OldBinariesErr != nil {
+		fmt.Fprintf(&sb, "old plan info lists no binaries: %v\n", d.OldBinariesErr)
+	}
+	if d.NewBinariesErr != nil {
+		fmt.Fprintf(&sb, "new plan info lists no binaries: %v\n", d.NewBinariesErr)
+	}
+
+	for _, b := range d.Binaries {
+		switch {
+		case b.Old == nil:
+			sb.WriteString(added("+ binary %s: %s (checksum %s)\n", b.Platform, b.New.URL, valueOrNone(b.New.Checksum)))
+		case b.New == nil:
+			sb.WriteString(removed("- binary %s: %s (checksum %s)\n", b.Platform, b.Old.URL, valueOrNone(b.Old.Checksum)))
+		default:
+			sb.WriteString(changed("~ binary %s:\n", b.Platform))
+			if b.Old.URL != b.New.URL {
+				sb.WriteString(changed("    url: %s -> %s\n", b.Old.URL, b.New.URL))
+			}
+			if b.ChecksumChanged() {
+				sb.WriteString(significant("  ! checksum: %s -> %s\n", valueOrNone(b.Old.Checksum), valueOrNone(b.New.Checksum)))
+			}
+		}
+	}
+
+	if d.ChecksumsChanged() {
+		sb.WriteString(significant("WARNING: binary checksums changed, a different binary will be installed\n"))
+	}
+
+	return sb.String()
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	upgradetypes "cosmossdk.io/x/upgrade/types"
+	"github.com/upnodedev/cosmos-sdk/tools/cosmovisor"
+)
+
+func TestFormatUpgradeInfoDiff(t *testing.T) {
+	diff := cosmovisor.UpgradeInfoDiff{
+		Old:    upgradetypes.Plan{Name: "chain2", Height: 49},
+		New:    upgradetypes.Plan{Name: "chain2", Height: 50},
+		Fields: []cosmovisor.FieldChange{{Field: "height", Old: "49", New: "50"}},
+		Binaries: []cosmovisor.BinaryChange{
+			{Platform: "darwin/arm64", Old: &cosmovisor.BinaryRef{URL: "https://example.com/darwin"}},
+			{Platform: "linux/amd64", Old: &cosmovisor.BinaryRef{URL: "https://example.com/linux", Checksum: "sha256:aaaa"}, New: &cosmovisor.BinaryRef{URL: "https://example.com/linux", Checksum: "sha256:cccc"}},
+			{Platform: "linux/arm64", New: &cosmovisor.BinaryRef{URL: "https://example.com/arm", Checksum: "sha256:dddd"}},
+		},
+	}
+
+	out := formatUpgradeInfoDiff("old.json", "new.json", diff, false)
+	require.Equal(t, `--- old.json
++++ new.json
+~ height: 49 -> 50
+- binary darwin/arm64: https://example.com/darwin (checksum none)
+~ binary linux/amd64:
+  ! checksum: sha256:aaaa -> sha256:cccc
++ binary linux/arm64: https://example.com/arm (checksum sha256:dddd)
+WARNING: binary checksums changed, a different binary will be installed
+`, out)
+
+	// the significant changes stand out
+	colored := formatUpgradeInfoDiff("old.json", "new.json", diff, true)
+	require.Contains(t, colored, "\x1b[31;1m  ! checksum: sha256:aaaa -> sha256:cccc\n\x1b[0m")
+	require.Contains(t, colored, "\x1b[32m+ binary linux/arm64")
+
+	diff.NewBinariesErr = errors.New("plan info must not be blank")
+	require.Contains(t, formatUpgradeInfoDiff("old.json", "new.json", diff, false), "new plan info lists no binaries: plan info must not be blank\n")
+}
+
+func TestFormatUpgradeInfoDiffEmpty(t *testing.T) {
+	out := formatUpgradeInfoDiff("old.json", "new.json", cosmovisor.UpgradeInfoDiff{}, false)
+	require.Equal(t, "--- old.json\n+++ new.json\nNo differences.\n", out)
+}
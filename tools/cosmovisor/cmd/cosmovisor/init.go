@@ -108,6 +108,9 @@ func getConfigForInitCmd() (*cosmovisor.Config, error) {
 	if cfg.TimeFormatLogs, err = cosmovisor.TimeFormatOptionFromEnv(cosmovisor.EnvTimeFormatLogs, time.Kitchen); err != nil {
 		errs = append(errs, err)
 	}
+	if cfg.LogFormat, err = cosmovisor.LogFormatOptionFromEnv(cosmovisor.EnvLogFormat, cosmovisor.LogFormatText); err != nil {
+		errs = append(errs, err)
+	}
 
 	if len(cfg.Name) == 0 {
 		errs = append(errs, fmt.Errorf("%s is not set", cosmovisor.EnvName))
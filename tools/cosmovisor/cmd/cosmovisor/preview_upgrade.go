@@ -0,0 +1,121 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/upnodedev/cosmos-sdk/tools/cosmovisor"
+)
+
+func NewPreviewUpgradeCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:          "preview-upgrade",
+		Short:        "Show what cosmovisor would do for the upgrade in upgrade-info.json, without triggering it.",
+		SilenceUsage: true,
+		Args:         cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := cosmovisor.GetConfigFromEnv()
+			if err != nil {
+				return err
+			}
+
+			preview, err := cosmovisor.PreviewUpgrade(cfg)
+			if err != nil {
+				return fmt.Errorf("failed to read the upgrade info: %w", err)
+			}
+
+			cmd.Print(formatUpgradePreview(preview))
+			return nil
+		},
+	}
+}
+
+func formatUpgradePreview(p cosmovisor.UpgradePreview) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "File: %s\n", p.File)
+	fmt.Fprintf(&sb, "Upgrade: %s\n", p.Plan.Name)
+	fmt.Fprintf(&sb, "Height: %d\n", p.Plan.Height)
+	fmt.Fprintf(&sb, "Upgrade Bin: %s\n", p.UpgradeBin)
+	if p.Installed {
+		sb.WriteString("Installed: yes, the upgrade binary is used as is\n")
+	} else {
+		sb.WriteString("Installed: no\n")
+	}
+
+	switch {
+	case p.BinariesErr != nil:
+		fmt.Fprintf(&sb, "Binary: none, the plan info lists no binaries: %v\n", p.BinariesErr)
+	case p.Platform == "":
+		fmt.Fprintf(&sb, "Binary: none matches the current platform (looked for %s)\n", strings.Join(p.Platforms, ", "))
+	default:
+		fmt.Fprintf(&sb, "Platform: %s\n", p.Platform)
+		fmt.Fprintf(&sb, "URL: %s\n", p.Binary.URL)
+		fmt.Fprintf(&sb, "Checksum: %s\n", valueOrNone(p.Binary.Checksum))
+		fmt.Fprintf(&sb, "Version: %s\n", valueOrNone(p.Version))
+		fmt.Fprintf(&sb, "Repo: %s\n", valueOrNone(p.Repo))
+		sb.WriteString("Manual install:\n")
+		for _, command := range manualInstallCommands(p) {
+			fmt.Fprintf(&sb, "  %s\n", command)
+		}
+	}
+
+	return sb.String()
+}
+
+// manualInstallCommands are the shell commands downloading the selected binary, verifying its
+// checksum and adding it as the upgrade binary.
+func manualInstallCommands(p cosmovisor.UpgradePreview) []string {
+	file := "./" + p.Plan.Name
+	commands := []string{fmt.Sprintf("curl -fL -o %s %s", file, shellQuote(p.Binary.URL))}
+
+	if algo, sum, ok := strings.Cut(p.Binary.Checksum, ":"); ok && checksumTools[algo] != "" {
+		commands = append(commands, fmt.Sprintf("echo %s | %s -c -", shellQuote(sum+"  "+file), checksumTools[algo]))
+	} else if p.Binary.Checksum != "" {
+		commands = append(commands, fmt.Sprintf("# verify %s against the checksum %s", file, p.Binary.Checksum))
+	}
+
+	if upgradeBinaryArchive(p.Binary.URL) {
+		commands = append(commands, fmt.Sprintf("# the download is an archive, extract the executable from it and use its path below instead of %s", file))
+	}
+
+	return append(commands,
+		fmt.Sprintf("chmod +x %s", file),
+		fmt.Sprintf("cosmovisor add-upgrade %s %s", p.Plan.Name, file),
+	)
+}
+
+// checksumTools are the commands verifying the checksums of a binary URL, by algorithm.
+var checksumTools = map[string]string{
+	"md5":    "md5sum",
+	"sha1":   "sha1sum",
+	"sha256": "sha256sum",
+	"sha512": "sha512sum",
+}
+
+// upgradeBinaryArchive reports whether the binary URL points to an archive, which is unpacked when
+// cosmovisor downloads it.
+func upgradeBinaryArchive(url string) bool {
+	url, _, _ = strings.Cut(url, "?")
+	for _, ext := range []string{".zip", ".tar", ".tar.gz", ".tgz", ".tar.bz2", ".tbz2", ".tar.xz", ".txz"} {
+		if strings.HasSuffix(url, ext) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// shellQuote quotes s for a POSIX shell.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+func valueOrNone(v string) string {
+	if v == "" {
+		return "none"
+	}
+
+	return v
+}
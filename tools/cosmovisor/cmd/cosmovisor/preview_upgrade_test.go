@@ -0,0 +1,45 @@
+package main
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	upgradetypes "cosmossdk.io/x/upgrade/types"
+	"github.com/upnodedev/cosmos-sdk/tools/cosmovisor"
+)
+
+func TestFormatUpgradePreview(t *testing.T) {
+	preview := cosmovisor.UpgradePreview{
+		File:       "/home/data/upgrade-info.json",
+		Plan:       upgradetypes.Plan{Name: "chain2", Height: 49},
+		Platforms:  []string{"linux/amd64", "any"},
+		Platform:   "any",
+		Binary:     cosmovisor.BinaryRef{URL: "https://example.com/chaind"},
+		UpgradeBin: "/home/cosmovisor/upgrades/chain2/bin/simd",
+	}
+
+	out := formatUpgradePreview(preview)
+	require.Contains(t, out, "File: /home/data/upgrade-info.json\nUpgrade: chain2\nHeight: 49\n")
+	require.Contains(t, out, "Installed: no\n")
+	require.Contains(t, out, "Platform: any\nURL: https://example.com/chaind\nChecksum: none\n")
+	require.Contains(t, out, "Manual install:\n"+
+		"  curl -fL -o ./chain2 'https://example.com/chaind'\n"+
+		"  chmod +x ./chain2\n"+
+		"  cosmovisor add-upgrade chain2 ./chain2\n")
+
+	preview.Binary = cosmovisor.BinaryRef{URL: "https://example.com/chaind.tar.gz?x=1", Checksum: "sha256:abcd"}
+	out = formatUpgradePreview(preview)
+	require.Contains(t, out, "  curl -fL -o ./chain2 'https://example.com/chaind.tar.gz?x=1'\n"+
+		"  echo 'abcd  ./chain2' | sha256sum -c -\n"+
+		"  # the download is an archive")
+
+	preview.Platform = ""
+	out = formatUpgradePreview(preview)
+	require.Contains(t, out, "Binary: none matches the current platform (looked for linux/amd64, any)\n")
+	require.NotContains(t, out, "URL:")
+
+	preview.BinariesErr = errors.New("plan info must not be blank")
+	require.Contains(t, formatUpgradePreview(preview), "the plan info lists no binaries: plan info must not be blank")
+}
@@ -17,6 +17,9 @@ func NewRootCmd() *cobra.Command {
 		configCmd,
 		NewVersionCmd(),
 		NewAddUpgradeCmd(),
+		NewPreviewUpgradeCmd(),
+		NewDiffUpgradeInfoCmd(),
+		NewSelfTestCmd(),
 	)
 
 	return rootCmd
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/upnodedev/cosmos-sdk/tools/cosmovisor"
+)
+
+func NewSelfTestCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:          "selftest",
+		Short:        "Exercise the upgrade detection and callbacks end to end with a synthetic upgrade.",
+		SilenceUsage: true,
+		Args:         cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := cosmovisor.GetConfigFromEnv()
+			if err != nil {
+				return err
+			}
+
+			results, err := cosmovisor.SelfTest(cfg, cfg.Logger(cmd.ErrOrStderr()))
+			if err != nil {
+				return fmt.Errorf("failed to set up the self test: %w", err)
+			}
+
+			out, failed := formatSelfTestResults(results)
+			cmd.Print(out)
+			if failed > 0 {
+				return fmt.Errorf("%d of %d self test stages failed", failed, len(results))
+			}
+
+			return nil
+		},
+	}
+}
+
+func formatSelfTestResults(results []cosmovisor.SelfTestResult) (string, int) {
+	var sb strings.Builder
+	failed := 0
+	for _, r := range results {
+		if r.Err != nil {
+			failed++
+			// a stage may fail for several reasons, one per line
+			fmt.Fprintf(&sb, "FAIL %s: %s\n", r.Stage, strings.ReplaceAll(r.Err.Error(), "\n", "\n  "))
+			continue
+		}
+		fmt.Fprintf(&sb, "PASS %s\n", r.Stage)
+	}
+
+	return sb.String(), failed
+}
@@ -0,0 +1,20 @@
+package main
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/upnodedev/cosmos-sdk/tools/cosmovisor"
+)
+
+func TestFormatSelfTestResults(t *testing.T) {
+	out, failed := formatSelfTestResults([]cosmovisor.SelfTestResult{
+		{Stage: cosmovisor.SelfTestStageParse},
+		{Stage: cosmovisor.SelfTestStageHeightGate},
+		{Stage: cosmovisor.SelfTestStageCallback, Err: errors.Join(errors.New("detected callback: not sent"), errors.New("reached callback: not sent"))},
+	})
+	require.Equal(t, 1, failed)
+	require.Equal(t, "PASS parse\nPASS height gate\nFAIL callback: detected callback: not sent\n  reached callback: not sent\n", out)
+}
@@ -0,0 +1,113 @@
+package cosmovisor
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+// upgradeConfirmation gates an upgrade whose height is reached on an operator confirmation,
+// given either by writing the upgrade name to the confirmation file or by a POST to /confirm.
+type upgradeConfirmation struct {
+	file    string
+	timeout time.Duration
+	now     func() time.Time
+
+	mu        sync.Mutex
+	pending   string // name of the upgrade awaiting confirmation
+	since     time.Time
+	confirmed bool
+	timedOut  bool
+}
+
+func newUpgradeConfirmation(cfg *Config) *upgradeConfirmation {
+	if !cfg.RequireConfirmation {
+		return nil
+	}
+
+	return &upgradeConfirmation{
+		file:    cfg.ConfirmationFilePath(),
+		timeout: cfg.ConfirmationTimeout,
+		now:     time.Now,
+	}
+}
+
+// await starts waiting for the confirmation of the named upgrade.
+func (c *upgradeConfirmation) await(name string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.pending = name
+	c.since = c.now()
+	c.confirmed = false
+	c.timedOut = false
+}
+
+// awaiting reports whether an upgrade is waiting for confirmation.
+func (c *upgradeConfirmation) awaiting() bool {
+	if c == nil {
+		return false
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.pending != ""
+}
+
+// confirm confirms the named upgrade. It fails if that upgrade is not the one awaiting confirmation.
+func (c *upgradeConfirmation) confirm(name string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.pending == "" || c.pending != name {
+		return fmt.Errorf("upgrade %q is not awaiting confirmation", name)
+	}
+
+	c.confirmed = true
+	return nil
+}
+
+// check reports whether the pending upgrade was confirmed, and whether the timeout elapsed for
+// the first time without a confirmation.
+func (c *upgradeConfirmation) check() (confirmed, timedOut bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !c.confirmed {
+		if bz, err := os.ReadFile(c.file); err == nil && string(bytes.TrimSpace(bz)) == c.pending {
+			c.confirmed = true
+		}
+	}
+
+	if c.confirmed {
+		c.pending = ""
+		_ = os.Remove(c.file)
+		return true, false
+	}
+
+	if c.timeout > 0 && !c.timedOut && c.now().Sub(c.since) >= c.timeout {
+		c.timedOut = true
+		return false, true
+	}
+
+	return false, false
+}
+
+// ServeHTTP confirms the upgrade named by the name query parameter.
+func (c *upgradeConfirmation) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	if err := c.confirm(r.URL.Query().Get("name")); err != nil {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
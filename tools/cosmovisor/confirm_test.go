@@ -0,0 +1,138 @@
+package cosmovisor
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	upgradetypes "cosmossdk.io/x/upgrade/types"
+)
+
+func newConfirmationWatcher(t *testing.T, cfg *Config) (*fileWatcher, *time.Time) {
+	t.Helper()
+
+	cfg.Home = t.TempDir()
+	cfg.Name = "dummyd"
+	cfg.RequireConfirmation = true
+	fw := newTestWatcher(t, cfg)
+	fw.getHeight = func() (int64, error) { return 49, nil }
+
+	now := time.Unix(0, 0)
+	fw.confirmation.now = func() time.Time { return now }
+	writeUpgradeInfo(t, cfg, upgradetypes.Plan{Name: "chain2", Height: 49})
+
+	return fw, &now
+}
+
+func TestUpgradeConfirmationFile(t *testing.T) {
+	srv := newCallbackRecorder(t)
+	cfg := &Config{CallbackAPI: srv.URL}
+	fw, _ := newConfirmationWatcher(t, cfg)
+
+	// the reached callback is emitted but the upgrade is held
+	require.False(t, fw.CheckUpdate(upgradetypes.Plan{}))
+	require.False(t, fw.CheckUpdate(upgradetypes.Plan{}))
+	require.Len(t, srv.received("/internal/cosmos///"+callbackPaths[CallbackEventReached]), 1)
+
+	// a confirmation for another upgrade is ignored
+	require.NoError(t, os.WriteFile(cfg.ConfirmationFilePath(), []byte("chain3\n"), 0o600))
+	require.False(t, fw.CheckUpdate(upgradetypes.Plan{}))
+
+	require.NoError(t, os.WriteFile(cfg.ConfirmationFilePath(), []byte("chain2\n"), 0o600))
+	require.True(t, fw.CheckUpdate(upgradetypes.Plan{}))
+	require.True(t, fw.CheckUpdate(upgradetypes.Plan{}))
+	require.NoFileExists(t, cfg.ConfirmationFilePath())
+	require.Len(t, srv.received("/internal/cosmos///"+callbackPaths[CallbackEventReached]), 1)
+}
+
+func TestUpgradeConfirmationHTTP(t *testing.T) {
+	fw, _ := newConfirmationWatcher(t, &Config{})
+	srv := httptest.NewServer(fw.confirmation)
+	defer srv.Close()
+
+	// nothing to confirm before the upgrade height is reached
+	resp, err := http.Post(srv.URL+"/confirm?name=chain2", "", nil)
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusConflict, resp.StatusCode)
+
+	require.False(t, fw.CheckUpdate(upgradetypes.Plan{}))
+
+	resp, err = http.Get(srv.URL + "/confirm?name=chain2")
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusMethodNotAllowed, resp.StatusCode)
+
+	resp, err = http.Post(srv.URL+"/confirm?name=chain3", "", nil)
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusConflict, resp.StatusCode)
+	require.False(t, fw.CheckUpdate(upgradetypes.Plan{}))
+
+	resp, err = http.Post(srv.URL+"/confirm?name=chain2", "", nil)
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.True(t, fw.CheckUpdate(upgradetypes.Plan{}))
+}
+
+func TestUpgradeConfirmationTimeout(t *testing.T) {
+	for _, abort := range []bool{false, true} {
+		srv := newCallbackRecorder(t)
+		cfg := &Config{CallbackAPI: srv.URL, ConfirmationTimeout: time.Minute, ConfirmationAbort: abort}
+		fw, now := newConfirmationWatcher(t, cfg)
+		timeouts := "/internal/cosmos///" + callbackPaths[CallbackEventNotConfirmed]
+
+		require.False(t, fw.CheckUpdate(upgradetypes.Plan{}))
+		*now = now.Add(59 * time.Second)
+		require.False(t, fw.CheckUpdate(upgradetypes.Plan{}))
+		require.Empty(t, srv.received(timeouts))
+
+		// the timeout is reported once
+		*now = now.Add(time.Second)
+		require.False(t, fw.CheckUpdate(upgradetypes.Plan{}))
+		*now = now.Add(time.Minute)
+		require.False(t, fw.CheckUpdate(upgradetypes.Plan{}))
+		got := srv.received(timeouts)
+		require.Len(t, got, 1)
+		require.Equal(t, "chain2", got[0].Name)
+
+		// a late confirmation only goes through when holding
+		require.NoError(t, os.WriteFile(cfg.ConfirmationFilePath(), []byte("chain2"), 0o600))
+		require.Equal(t, !abort, fw.CheckUpdate(upgradetypes.Plan{}), "abort: %t", abort)
+	}
+}
+
+func TestUpgradeConfirmationAfterExit(t *testing.T) {
+	cfg := &Config{}
+	fw, _ := newConfirmationWatcher(t, cfg)
+
+	// the app exits while the upgrade awaits its confirmation, which comes in later
+	polls := 0
+	fw.sleep = func(time.Duration) {
+		polls++
+		if polls == 3 {
+			require.NoError(t, os.WriteFile(cfg.ConfirmationFilePath(), []byte("chain2"), 0o600))
+		}
+	}
+	require.True(t, fw.checkAfterExit(upgradetypes.Plan{}))
+	require.Equal(t, 3, polls)
+}
+
+func TestUpgradeConfirmationAbortedAfterExit(t *testing.T) {
+	cfg := &Config{ConfirmationTimeout: time.Minute, ConfirmationAbort: true}
+	fw, now := newConfirmationWatcher(t, cfg)
+
+	polls := 0
+	fw.sleep = func(time.Duration) {
+		polls++
+		*now = now.Add(20 * time.Second)
+	}
+	require.False(t, fw.checkAfterExit(upgradetypes.Plan{}))
+	require.Equal(t, 3, polls)
+	require.False(t, fw.confirmation.awaiting())
+}
@@ -0,0 +1,96 @@
+package cosmovisor
+
+import (
+	"encoding/json"
+	"os"
+	"time"
+
+	upgradetypes "cosmossdk.io/x/upgrade/types"
+)
+
+// reasons of the decisions recorded in the decision log, besides reasonHeightNotReached
+const (
+	reasonHeightUnknown  = "height_unknown"
+	reasonHeightReached  = "height_reached"
+	reasonPlanAmended    = "plan_amended"
+	reasonPlanRolledBack = "plan_rolled_back"
+	reasonAlreadyHandled = "already_handled"
+	reasonInvalidPlan    = "invalid_plan"
+	reasonCosmovisorOld  = "cosmovisor_too_old"
+
+	// decisions about an upgrade past its height, held or awaiting its confirmation
+	reasonAwaitingConfirmation = "awaiting_confirmation"
+	reasonConfirmed            = "confirmed"
+	reasonNotConfirmed         = "not_confirmed"
+	reasonDiskSpaceLow         = "disk_space_low"
+	reasonGateClosed           = "gate_closed"
+	reasonIntervalNotElapsed   = "interval_not_elapsed"
+	reasonSignalLimit          = "signal_limit_reached"
+	reasonHoldReleased         = "hold_released"
+	reasonHookFailed           = "hook_failed"
+)
+
+// decisionRecord is a line of the decision log, recording what the watcher made of a plan.
+type decisionRecord struct {
+	Time          time.Time         `json:"time"`
+	File          string            `json:"file"`
+	FileModTime   time.Time         `json:"file_mod_time"`
+	Plan          upgradetypes.Plan `json:"plan"`
+	CurrentHeight int64             `json:"current_height"`
+	// Upgrade is true if the upgrade is needed. A plan at its height may still be held,
+	// e.g. while awaiting a confirmation.
+	Upgrade bool   `json:"upgrade"`
+	Reason  string `json:"reason"`
+	Error   string `json:"error,omitempty"`
+}
+
+// sameDecision reports whether two records hold the same decision about the same plan at the
+// same height, whatever the time they were made at.
+func sameDecision(a, b decisionRecord) bool {
+	return a.File == b.File && a.FileModTime.Equal(b.FileModTime) && a.Plan.String() == b.Plan.String() &&
+		a.CurrentHeight == b.CurrentHeight && a.Upgrade == b.Upgrade && a.Reason == b.Reason && a.Error == b.Error
+}
+
+// logUpgradeDecision records a decision about the upgrade of info, past its height.
+func (fw *fileWatcher) logUpgradeDecision(info upgradetypes.Plan, upgrade bool, reason, errMsg string) bool {
+	fw.logDecision(decisionRecord{
+		File:          fw.filename,
+		FileModTime:   fw.lastModTime,
+		Plan:          info,
+		CurrentHeight: fw.lastHeight,
+		Upgrade:       upgrade,
+		Reason:        reason,
+		Error:         errMsg,
+	})
+	return upgrade
+}
+
+// logDecision appends the decision to the decision log, if enabled. A decision repeating the
+// previous one at the same height, e.g. on the polls made within a block, is not recorded again,
+// so the log grows by at most a few lines per block rather than per poll.
+// The file is opened in append mode for every record and each record is a single write, so the
+// log is never truncated or interleaved, including across restarts.
+func (fw *fileWatcher) logDecision(record decisionRecord) {
+	if fw.decisionLog == "" || sameDecision(record, fw.lastDecision) {
+		return
+	}
+	fw.lastDecision = record
+
+	record.Time = fw.now().UTC()
+	bz, err := json.Marshal(record)
+	if err != nil {
+		fw.logger.Error("failed to encode the upgrade decision", "error", err)
+		return
+	}
+
+	f, err := os.OpenFile(fw.decisionLog, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		fw.logger.Error("failed to open the decision log", "file", fw.decisionLog, "error", err)
+		return
+	}
+	defer f.Close()
+
+	if _, err := f.Write(append(bz, '\n')); err != nil {
+		fw.logger.Error("failed to write the decision log", "file", fw.decisionLog, "error", err)
+	}
+}
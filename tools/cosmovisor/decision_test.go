@@ -0,0 +1,137 @@
+package cosmovisor
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	upgradetypes "cosmossdk.io/x/upgrade/types"
+)
+
+func readDecisionLog(t *testing.T, path string) []decisionRecord {
+	t.Helper()
+
+	f, err := os.Open(path)
+	require.NoError(t, err)
+	defer f.Close()
+
+	var records []decisionRecord
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var record decisionRecord
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &record), "invalid decision log line %q", scanner.Text())
+		records = append(records, record)
+	}
+	require.NoError(t, scanner.Err())
+
+	return records
+}
+
+func TestDecisionLog(t *testing.T) {
+	require := require.New(t)
+
+	home := t.TempDir()
+	logPath := filepath.Join(home, "decisions.jsonl")
+	cfg := &Config{Home: home, Name: "dummyd", DecisionLogPath: logPath}
+	fw := newTestWatcher(t, cfg)
+	var height int64
+	fw.getHeight = func() (int64, error) { return height, nil }
+
+	plan := upgradetypes.Plan{Name: "chain2", Height: 100}
+	writeUpgradeInfo(t, cfg, plan)
+	height = 50
+	require.False(fw.CheckUpdate(upgradetypes.Plan{Name: "chain1"}))
+	records := readDecisionLog(t, logPath)
+	require.Len(records, 1)
+	require.Equal(fw.filename, records[0].File)
+	require.Equal(plan.Name, records[0].Plan.Name)
+	require.Equal(plan.Height, records[0].Plan.Height)
+	require.Equal(int64(50), records[0].CurrentHeight)
+	require.False(records[0].Upgrade)
+	require.Equal(reasonHeightNotReached, records[0].Reason)
+	require.False(records[0].Time.IsZero())
+	require.False(records[0].FileModTime.IsZero())
+
+	// the same decision on the next polls at the same height is not recorded again
+	require.False(fw.CheckUpdate(upgradetypes.Plan{Name: "chain1"}))
+	require.Len(readDecisionLog(t, logPath), 1)
+
+	// it is at a new height
+	height = 60
+	require.False(fw.CheckUpdate(upgradetypes.Plan{Name: "chain1"}))
+	records = readDecisionLog(t, logPath)
+	require.Len(records, 2)
+	require.Equal(reasonHeightNotReached, records[1].Reason)
+	require.Equal(int64(60), records[1].CurrentHeight)
+
+	height = 100
+	require.True(fw.CheckUpdate(upgradetypes.Plan{Name: "chain1"}))
+	records = readDecisionLog(t, logPath)
+	require.Len(records, 3)
+	require.True(records[2].Upgrade)
+	require.Equal(reasonHeightReached, records[2].Reason)
+	require.Equal(int64(100), records[2].CurrentHeight)
+
+	// a restarted watcher appends to the existing log
+	fw = newTestWatcher(t, cfg)
+	fw.getHeight = func() (int64, error) { return 100, nil }
+	require.False(fw.CheckUpdate(upgradetypes.Plan{Name: "chain2"}))
+	records = readDecisionLog(t, logPath)
+	require.Len(records, 4)
+	require.Equal(reasonAlreadyHandled, records[3].Reason)
+}
+
+func TestDecisionLogDisabled(t *testing.T) {
+	cfg := &Config{Home: t.TempDir(), Name: "dummyd"}
+	fw := newTestWatcher(t, cfg)
+	fw.getHeight = func() (int64, error) { return 50, nil }
+
+	writeUpgradeInfo(t, cfg, upgradetypes.Plan{Name: "chain2", Height: 100})
+	require.False(t, fw.CheckUpdate(upgradetypes.Plan{}))
+	require.Zero(t, fw.lastDecision.Reason)
+}
+
+func TestDecisionLogHeld(t *testing.T) {
+	require := require.New(t)
+
+	home := t.TempDir()
+	logPath := filepath.Join(home, "decisions.jsonl")
+	cfg := &Config{Home: home, Name: "dummyd", DecisionLogPath: logPath, RequireConfirmation: true, MinFreeDiskBytes: 1000}
+	fw := newTestWatcher(t, cfg)
+	fw.getHeight = func() (int64, error) { return 100, nil }
+	free := uint64(999)
+	fw.diskFree = func(string) (uint64, error) { return free, nil }
+	reasons := func() []string {
+		var reasons []string
+		for _, record := range readDecisionLog(t, logPath) {
+			reasons = append(reasons, record.Reason)
+		}
+		return reasons
+	}
+
+	// the decisions made while awaiting the confirmation and then held are recorded, the
+	// repeated ones once
+	writeUpgradeInfo(t, cfg, upgradetypes.Plan{Name: "chain2", Height: 100})
+	require.False(fw.CheckUpdate(upgradetypes.Plan{Name: "chain1"}))
+	require.Equal([]string{reasonAwaitingConfirmation, reasonHeightReached}, reasons())
+	require.False(fw.CheckUpdate(upgradetypes.Plan{Name: "chain1"}))
+	require.False(fw.CheckUpdate(upgradetypes.Plan{Name: "chain1"}))
+	require.Equal([]string{reasonAwaitingConfirmation, reasonHeightReached, reasonAwaitingConfirmation}, reasons())
+
+	require.NoError(os.WriteFile(cfg.ConfirmationFilePath(), []byte("chain2"), 0o600))
+	require.False(fw.CheckUpdate(upgradetypes.Plan{Name: "chain1"}))
+	require.False(fw.CheckUpdate(upgradetypes.Plan{Name: "chain1"}))
+	require.Equal([]string{reasonAwaitingConfirmation, reasonHeightReached, reasonAwaitingConfirmation, reasonDiskSpaceLow}, reasons())
+
+	free = 1000
+	require.True(fw.CheckUpdate(upgradetypes.Plan{Name: "chain1"}))
+	records := readDecisionLog(t, logPath)
+	require.Len(records, 5)
+	require.Equal(reasonHoldReleased, records[4].Reason)
+	require.True(records[4].Upgrade)
+	require.Equal("chain2", records[4].Plan.Name)
+}
@@ -0,0 +1,127 @@
+package cosmovisor
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+
+	upgradetypes "cosmossdk.io/x/upgrade/types"
+)
+
+// FieldChange is a plan field differing between two upgrade-info.json files.
+type FieldChange struct {
+	Field string
+	Old   string
+	New   string
+}
+
+// BinaryChange is a platform binary differing between two upgrade-info.json files. Old or New
+// is nil when the platform is only listed by the other file.
+type BinaryChange struct {
+	Platform string
+	Old      *BinaryRef
+	New      *BinaryRef
+}
+
+// ChecksumChanged reports whether the platform is listed by both files with different checksums,
+// meaning a different binary is expected.
+func (c BinaryChange) ChecksumChanged() bool {
+	return c.Old != nil && c.New != nil && c.Old.Checksum != c.New.Checksum
+}
+
+// UpgradeInfoDiff is the difference between two upgrade-info.json files.
+type UpgradeInfoDiff struct {
+	Old upgradetypes.Plan
+	New upgradetypes.Plan
+	// Fields are the changed plan fields. The info field is only compared as a whole when the
+	// binaries of either plan cannot be parsed from it.
+	Fields []FieldChange
+	// Binaries are the changed binaries, by platform.
+	Binaries []BinaryChange
+	// OldBinariesErr and NewBinariesErr are set when the plan info doesn't list binaries.
+	OldBinariesErr error
+	NewBinariesErr error
+}
+
+// Empty reports whether the files describe the same upgrade.
+func (d UpgradeInfoDiff) Empty() bool {
+	return len(d.Fields) == 0 && len(d.Binaries) == 0
+}
+
+// ChecksumsChanged reports whether a binary listed by both files changed checksum.
+func (d UpgradeInfoDiff) ChecksumsChanged() bool {
+	for _, c := range d.Binaries {
+		if c.ChecksumChanged() {
+			return true
+		}
+	}
+
+	return false
+}
+
+// DiffUpgradeInfo parses the two upgrade-info.json files as cosmovisor does when watching for
+// upgrades and returns what changed from the old one to the new one.
+func DiffUpgradeInfo(cfg *Config, oldPath, newPath string) (UpgradeInfoDiff, error) {
+	schema, err := loadUpgradeInfoSchema(cfg.UpgradeInfoSchemaPath)
+	if err != nil {
+		return UpgradeInfoDiff{}, err
+	}
+	opts := append(cfg.UpgradeInfoParseOptions(), ParseOptionSchema(schema))
+
+	oldPlan, err := ParseUpgradeInfoFile(oldPath, opts...)
+	if err != nil {
+		return UpgradeInfoDiff{}, fmt.Errorf("failed to read %s: %w", oldPath, err)
+	}
+	newPlan, err := ParseUpgradeInfoFile(newPath, opts...)
+	if err != nil {
+		return UpgradeInfoDiff{}, fmt.Errorf("failed to read %s: %w", newPath, err)
+	}
+
+	return diffPlans(oldPlan, newPlan), nil
+}
+
+func diffPlans(oldPlan, newPlan upgradetypes.Plan) UpgradeInfoDiff {
+	d := UpgradeInfoDiff{Old: oldPlan, New: newPlan}
+	if oldPlan.Name != newPlan.Name {
+		d.Fields = append(d.Fields, FieldChange{Field: "name", Old: oldPlan.Name, New: newPlan.Name})
+	}
+	if oldPlan.Height != newPlan.Height {
+		d.Fields = append(d.Fields, FieldChange{Field: "height", Old: strconv.FormatInt(oldPlan.Height, 10), New: strconv.FormatInt(newPlan.Height, 10)})
+	}
+
+	var oldBinaries, newBinaries map[string]BinaryRef
+	oldBinaries, d.OldBinariesErr = ParseUpgradeBinaries(oldPlan.Info)
+	newBinaries, d.NewBinariesErr = ParseUpgradeBinaries(newPlan.Info)
+	if d.OldBinariesErr != nil || d.NewBinariesErr != nil {
+		if oldPlan.Info != newPlan.Info {
+			d.Fields = append(d.Fields, FieldChange{Field: "info", Old: oldPlan.Info, New: newPlan.Info})
+		}
+	}
+
+	platforms := make(map[string]bool, len(oldBinaries)+len(newBinaries))
+	for platform := range oldBinaries {
+		platforms[platform] = true
+	}
+	for platform := range newBinaries {
+		platforms[platform] = true
+	}
+	for platform := range platforms {
+		oldRef, inOld := oldBinaries[platform]
+		newRef, inNew := newBinaries[platform]
+		if inOld && inNew && oldRef == newRef {
+			continue
+		}
+
+		c := BinaryChange{Platform: platform}
+		if inOld {
+			c.Old = &oldRef
+		}
+		if inNew {
+			c.New = &newRef
+		}
+		d.Binaries = append(d.Binaries, c)
+	}
+	sort.Slice(d.Binaries, func(i, j int) bool { return d.Binaries[i].Platform < d.Binaries[j].Platform })
+
+	return d
+}
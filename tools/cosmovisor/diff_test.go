@@ -0,0 +1,116 @@
+package cosmovisor
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	upgradetypes "cosmossdk.io/x/upgrade/types"
+)
+
+func TestDiffPlans(t *testing.T) {
+	const (
+		linux  = `"linux/amd64":"https://example.com/chaind-linux?checksum=sha256:aaaa"`
+		darwin = `"darwin/arm64":"https://example.com/chaind-darwin?checksum=sha256:bbbb"`
+	)
+	binaries := func(refs ...string) string {
+		info := `{"binaries":{`
+		for i, ref := range refs {
+			if i > 0 {
+				info += ","
+			}
+			info += ref
+		}
+		return info + `}}`
+	}
+	plan := upgradetypes.Plan{Name: "chain2", Height: 49, Info: binaries(linux, darwin)}
+
+	cases := map[string]struct {
+		newPlan          upgradetypes.Plan
+		expectFields     []FieldChange
+		expectBinaries   []BinaryChange
+		expectChecksums  bool
+		expectBinaryErrs bool
+	}{
+		"identical": {
+			newPlan: plan,
+		},
+		"same binaries listed differently": {
+			newPlan: upgradetypes.Plan{Name: "chain2", Height: 49, Info: binaries(darwin, linux)},
+		},
+		"name and height": {
+			newPlan: upgradetypes.Plan{Name: "chain3", Height: 50, Info: plan.Info},
+			expectFields: []FieldChange{
+				{Field: "name", Old: "chain2", New: "chain3"},
+				{Field: "height", Old: "49", New: "50"},
+			},
+		},
+		"binary added and removed": {
+			newPlan: upgradetypes.Plan{Name: "chain2", Height: 49, Info: binaries(linux, `"linux/arm64":"https://example.com/chaind-arm"`)},
+			expectBinaries: []BinaryChange{
+				{Platform: "darwin/arm64", Old: &BinaryRef{URL: "https://example.com/chaind-darwin", Checksum: "sha256:bbbb"}},
+				{Platform: "linux/arm64", New: &BinaryRef{URL: "https://example.com/chaind-arm"}},
+			},
+		},
+		"url changed": {
+			newPlan: upgradetypes.Plan{Name: "chain2", Height: 49, Info: binaries(`"linux/amd64":"https://mirror.example.com/chaind-linux?checksum=sha256:aaaa"`, darwin)},
+			expectBinaries: []BinaryChange{{
+				Platform: "linux/amd64",
+				Old:      &BinaryRef{URL: "https://example.com/chaind-linux", Checksum: "sha256:aaaa"},
+				New:      &BinaryRef{URL: "https://mirror.example.com/chaind-linux", Checksum: "sha256:aaaa"},
+			}},
+		},
+		"checksum changed": {
+			newPlan: upgradetypes.Plan{Name: "chain2", Height: 49, Info: binaries(`"linux/amd64":"https://example.com/chaind-linux?checksum=sha256:cccc"`, darwin)},
+			expectBinaries: []BinaryChange{{
+				Platform: "linux/amd64",
+				Old:      &BinaryRef{URL: "https://example.com/chaind-linux", Checksum: "sha256:aaaa"},
+				New:      &BinaryRef{URL: "https://example.com/chaind-linux", Checksum: "sha256:cccc"},
+			}},
+			expectChecksums: true,
+		},
+		"info without binaries": {
+			newPlan: upgradetypes.Plan{Name: "chain2", Height: 49, Info: "manual upgrade"},
+			expectFields: []FieldChange{
+				{Field: "info", Old: plan.Info, New: "manual upgrade"},
+			},
+			expectBinaries: []BinaryChange{
+				{Platform: "darwin/arm64", Old: &BinaryRef{URL: "https://example.com/chaind-darwin", Checksum: "sha256:bbbb"}},
+				{Platform: "linux/amd64", Old: &BinaryRef{URL: "https://example.com/chaind-linux", Checksum: "sha256:aaaa"}},
+			},
+			expectBinaryErrs: true,
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			d := diffPlans(plan, tc.newPlan)
+			require.Equal(t, tc.expectFields, d.Fields)
+			require.Equal(t, tc.expectBinaries, d.Binaries)
+			require.Equal(t, len(tc.expectFields) == 0 && len(tc.expectBinaries) == 0, d.Empty())
+			require.Equal(t, tc.expectChecksums, d.ChecksumsChanged())
+			require.NoError(t, d.OldBinariesErr)
+			require.Equal(t, tc.expectBinaryErrs, d.NewBinariesErr != nil)
+		})
+	}
+}
+
+func TestDiffUpgradeInfo(t *testing.T) {
+	dir := t.TempDir()
+	oldPath, newPath, invalidPath := filepath.Join(dir, "old.json"), filepath.Join(dir, "new.json"), filepath.Join(dir, "invalid.json")
+	require.NoError(t, os.WriteFile(oldPath, []byte(`{"name":"Chain2","height":49}`), 0o600))
+	require.NoError(t, os.WriteFile(newPath, []byte(`{"name":"chain2","height":50}`), 0o600))
+	require.NoError(t, os.WriteFile(invalidPath, []byte(`{"name":"chain2"}`), 0o600))
+
+	// both files go through the shared parser, recasing the names
+	d, err := DiffUpgradeInfo(&Config{}, oldPath, newPath)
+	require.NoError(t, err)
+	require.Equal(t, []FieldChange{{Field: "height", Old: "49", New: "50"}}, d.Fields)
+
+	_, err = DiffUpgradeInfo(&Config{}, oldPath, invalidPath)
+	require.ErrorIs(t, err, ErrUpgradeInfoInvalid)
+	_, err = DiffUpgradeInfo(&Config{}, filepath.Join(dir, "missing.json"), newPath)
+	require.Error(t, err)
+}
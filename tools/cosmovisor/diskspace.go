@@ -0,0 +1,87 @@
+package cosmovisor
+
+import (
+	"fmt"
+	"strings"
+
+	upgradetypes "cosmossdk.io/x/upgrade/types"
+)
+
+// checkDiskSpace reports whether the data and binaries directories have at least minFreeDisk
+// bytes available. Otherwise the upgrade is held and the disk space checked again on every
+// poll, a disk_space_low callback carrying the lowest free space being emitted once when the hold
+// starts.
+func (fw *fileWatcher) checkDiskSpace(info upgradetypes.Plan, callback callbackInfo) bool {
+	if fw.minFreeDisk <= 0 {
+		return true
+	}
+
+	reason, lowest := fw.lowDiskSpace()
+	if reason == "" {
+		if fw.diskHeld {
+			fw.logger.Info("enough disk space for the held upgrade", "name", info.Name)
+		}
+		fw.diskHeld = false
+		return true
+	}
+
+	fw.logger.Error("upgrade held", "name", info.Name, "height", info.Height, "reason", reason)
+	if !fw.diskHeld {
+		fw.diskHeld = true
+		fw.heldCallback = callback
+		callback.Error = reason
+		callback.FreeDiskBytes = lowest
+		_ = fw.callbacks.send(CallbackEventDiskSpaceLow, callback)
+	}
+
+	return false
+}
+
+// alertDiskSpace checks the disk space of a detected upgrade whose height is not reached yet,
+// emitting a disk_space_low callback once per plan if it is low, so the space can be freed before
+// the upgrade gets held.
+func (fw *fileWatcher) alertDiskSpace(info upgradetypes.Plan, callback callbackInfo) {
+	if fw.minFreeDisk <= 0 || (fw.diskAlerted.Name == info.Name && fw.diskAlerted.Height == info.Height) {
+		return
+	}
+
+	reason, lowest := fw.lowDiskSpace()
+	if reason == "" {
+		return
+	}
+
+	fw.logger.Error("not enough disk space for the upcoming upgrade", "name", info.Name, "height", info.Height, "reason", reason)
+	fw.diskAlerted = info
+	callback.Error = reason
+	callback.FreeDiskBytes = lowest
+	_ = fw.callbacks.send(CallbackEventDiskSpaceLow, callback)
+}
+
+// lowDiskSpace returns why the data and binaries directories do not have minFreeDisk bytes
+// available along with the lowest free space, or an empty reason if they do. A directory whose
+// free space cannot be read is skipped.
+func (fw *fileWatcher) lowDiskSpace() (string, uint64) {
+	var low []string
+	var lowest uint64
+	for _, dir := range fw.diskDirs {
+		free, err := fw.diskFree(dir)
+		if err != nil {
+			fw.logger.Error("failed to read the free disk space, skipping it", "dir", dir, "error", err)
+			continue
+		}
+		if free >= uint64(fw.minFreeDisk) {
+			continue
+		}
+
+		low = append(low, fmt.Sprintf("%s has %d bytes free", dir, free))
+		if lowest == 0 || free < lowest {
+			lowest = free
+		}
+	}
+
+	if len(low) == 0 {
+		return "", 0
+	}
+
+	return fmt.Sprintf("not enough disk space, %d bytes required: %s", fw.minFreeDisk, strings.Join(low, ", ")), lowest
+}
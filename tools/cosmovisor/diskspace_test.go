@@ -0,0 +1,104 @@
+package cosmovisor
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	upgradetypes "cosmossdk.io/x/upgrade/types"
+)
+
+func TestCheckUpdateDiskSpace(t *testing.T) {
+	require := require.New(t)
+
+	srv := newCallbackRecorder(t)
+	cfg := &Config{Home: t.TempDir(), Name: "dummyd", CallbackAPI: srv.URL, MinFreeDiskBytes: 1000}
+	fw := newTestWatcher(t, cfg)
+	fw.getHeight = func() (int64, error) { return 49, nil }
+	free := map[string]uint64{fw.diskDirs[0]: 5000, fw.diskDirs[1]: 999}
+	fw.diskFree = func(path string) (uint64, error) { return free[path], nil }
+	low := "/internal/cosmos///" + callbackPaths[CallbackEventDiskSpaceLow]
+
+	// a directory low on space holds the upgrade, and is checked again on every poll
+	writeUpgradeInfo(t, cfg, upgradetypes.Plan{Name: "chain2", Height: 49})
+	require.False(fw.CheckUpdate(upgradetypes.Plan{}))
+	require.False(fw.CheckUpdate(upgradetypes.Plan{}))
+	got := srv.received(low)
+	require.Len(got, 1)
+	require.Equal("chain2", got[0].Name)
+	require.Equal(uint64(999), got[0].FreeDiskBytes)
+	require.Contains(got[0].Error, cfg.Root()+" has 999 bytes free")
+	require.NotContains(got[0].Error, fw.diskDirs[0])
+
+	free[fw.diskDirs[1]] = 1000
+	require.True(fw.CheckUpdate(upgradetypes.Plan{}))
+	require.Len(srv.received(low), 1)
+}
+
+func TestCheckUpdateDiskSpaceEnough(t *testing.T) {
+	cfg := &Config{Home: t.TempDir(), Name: "dummyd", MinFreeDiskBytes: 1 << 20}
+	fw := newTestWatcher(t, cfg)
+	fw.getHeight = func() (int64, error) { return 49, nil }
+	fw.diskFree = func(string) (uint64, error) { return 1 << 30, nil }
+
+	writeUpgradeInfo(t, cfg, upgradetypes.Plan{Name: "chain2", Height: 49})
+	require.True(t, fw.CheckUpdate(upgradetypes.Plan{}))
+}
+
+func TestCheckUpdateDiskSpaceDetected(t *testing.T) {
+	require := require.New(t)
+
+	srv := newCallbackRecorder(t)
+	cfg := &Config{Home: t.TempDir(), Name: "dummyd", CallbackAPI: srv.URL, MinFreeDiskBytes: 1000}
+	fw := newTestWatcher(t, cfg)
+	height := int64(40)
+	fw.getHeight = func() (int64, error) { return height, nil }
+	fw.diskFree = func(string) (uint64, error) { return 999, nil }
+	low := "/internal/cosmos///" + callbackPaths[CallbackEventDiskSpaceLow]
+
+	// low disk space is reported once as soon as the upgrade is detected
+	writeUpgradeInfo(t, cfg, upgradetypes.Plan{Name: "chain2", Height: 49})
+	require.False(fw.CheckUpdate(upgradetypes.Plan{}))
+	require.False(fw.CheckUpdate(upgradetypes.Plan{}))
+	got := srv.received(low)
+	require.Len(got, 1)
+	require.Equal("chain2", got[0].Name)
+	require.Equal(uint64(999), got[0].FreeDiskBytes)
+	require.False(fw.diskHeld)
+
+	// and again once it holds the upgrade
+	height = 49
+	require.False(fw.CheckUpdate(upgradetypes.Plan{}))
+	require.Len(srv.received(low), 2)
+	require.True(fw.diskHeld)
+}
+
+func TestCheckUpdateDiskSpaceAfterExit(t *testing.T) {
+	cfg := &Config{Home: t.TempDir(), Name: "dummyd", MinFreeDiskBytes: 1000}
+	fw := newTestWatcher(t, cfg)
+	fw.getHeight = func() (int64, error) { return 49, nil }
+	free := uint64(999)
+	fw.diskFree = func(string) (uint64, error) { return free, nil }
+	writeUpgradeInfo(t, cfg, upgradetypes.Plan{Name: "chain2", Height: 49})
+
+	// the app exits while the upgrade is held, it goes through once space is freed
+	polls := 0
+	fw.sleep = func(time.Duration) {
+		polls++
+		if polls == 2 {
+			free = 1000
+		}
+	}
+	require.True(t, fw.checkAfterExit(upgradetypes.Plan{}))
+	require.Equal(t, 2, polls)
+}
+
+func TestDiskFree(t *testing.T) {
+	free, err := diskFree(t.TempDir())
+	require.NoError(t, err)
+	require.Positive(t, free)
+
+	_, err = diskFree("/does/not/exist")
+	require.Error(t, err)
+}
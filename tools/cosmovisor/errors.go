@@ -0,0 +1,21 @@
+package cosmovisor
+
+import "errors"
+
+// Errors returned when the upgrade-info.json file cannot be accepted.
+var (
+	// ErrUpgradeInfoEmpty is returned when the upgrade-info.json file is empty.
+	ErrUpgradeInfoEmpty = errors.New("empty upgrade-info.json")
+	// ErrUpgradeInfoInvalid is returned when the upgrade plan fails its basic validation.
+	ErrUpgradeInfoInvalid = errors.New("invalid upgrade-info.json content")
+	// ErrUpgradeInfoSchema is returned when the upgrade-info.json file does not match the configured JSON schema.
+	ErrUpgradeInfoSchema = errors.New("upgrade-info.json does not match schema")
+	// ErrUpgradeInfoTooLarge is returned when the upgrade-info.json file exceeds the size limit.
+	ErrUpgradeInfoTooLarge = errors.New("upgrade-info.json too large")
+	// ErrUpgradeInfoUnknownField is returned in strict mode when the upgrade-info.json file has a
+	// field the upgrade plan does not define.
+	ErrUpgradeInfoUnknownField = errors.New("unknown field in upgrade-info.json")
+	// ErrUpgradeInfoUnreadable is returned when the upgrade-info.json file exists but cannot be read
+	// for lack of permission.
+	ErrUpgradeInfoUnreadable = errors.New("upgrade-info.json unreadable")
+)
@@ -6,4 +6,5 @@ const (
 	FlagCosmovisorOnly    = "cosmovisor-only"
 	FlagForce             = "force"
 	FlagUpgradeHeight     = "upgrade-height"
+	FlagNoColor           = "no-color"
 )
@@ -0,0 +1,141 @@
+package cosmovisor
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	neturl "net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	upgradetypes "cosmossdk.io/x/upgrade/types"
+)
+
+// defaultUpgradeGateTimeout is used when Config.UpgradeGateTimeout is not set.
+const defaultUpgradeGateTimeout = 5 * time.Second
+
+// maxUpgradeGateResponse bounds the size of the upgrade gate response read.
+const maxUpgradeGateResponse = 4096
+
+// upgradeGate lets an upgrade whose height is reached proceed only while a feature flag
+// endpoint reports it enabled.
+type upgradeGate struct {
+	url      string
+	client   *http.Client
+	timeout  time.Duration
+	failOpen bool
+}
+
+func newUpgradeGate(cfg *Config) *upgradeGate {
+	if cfg.UpgradeGateURL == "" {
+		return nil
+	}
+
+	timeout := cfg.UpgradeGateTimeout
+	if timeout <= 0 {
+		timeout = defaultUpgradeGateTimeout
+	}
+
+	return &upgradeGate{
+		url:      cfg.UpgradeGateURL,
+		client:   newCallbackClient(cfg),
+		timeout:  timeout,
+		failOpen: cfg.UpgradeGateFailOpen,
+	}
+}
+
+// query reports whether the gate enables the upgrade to info. The gate URL is requested with the
+// upgrade name and height as query parameters, and must answer a 2xx status with a boolean body,
+// either plain (e.g. `true`, `0`) or as the enabled field of a JSON object.
+func (g *upgradeGate) query(info upgradetypes.Plan) (bool, error) {
+	u, err := neturl.Parse(g.url)
+	if err != nil {
+		return false, err
+	}
+	q := u.Query()
+	q.Set("name", info.Name)
+	q.Set("height", strconv.FormatInt(info.Height, 10))
+	u.RawQuery = q.Encode()
+
+	ctx, cancel := context.WithTimeout(context.Background(), g.timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return false, err
+	}
+	resp, err := g.client.Do(req)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return false, fmt.Errorf("upgrade gate returned status %s", resp.Status)
+	}
+
+	bz, err := io.ReadAll(io.LimitReader(resp.Body, maxUpgradeGateResponse))
+	if err != nil {
+		return false, err
+	}
+
+	return parseGateResponse(bz)
+}
+
+// parseGateResponse parses the boolean answered by the upgrade gate.
+func parseGateResponse(bz []byte) (bool, error) {
+	body := strings.TrimSpace(string(bz))
+	if enabled, err := strconv.ParseBool(body); err == nil {
+		return enabled, nil
+	}
+
+	var resp struct {
+		Enabled *bool `json:"enabled"`
+	}
+	if err := json.Unmarshal([]byte(body), &resp); err != nil || resp.Enabled == nil {
+		return false, fmt.Errorf("invalid upgrade gate response %q, expected a boolean or an object with an enabled field", body)
+	}
+
+	return *resp.Enabled, nil
+}
+
+// checkGate reports whether the upgrade gate lets the upgrade to info proceed. While it does not,
+// the upgrade is held and the gate queried again on every poll, an upgrade_held callback being
+// emitted once when the hold starts. A gate that cannot be queried lets the upgrade proceed only
+// if it fails open.
+func (fw *fileWatcher) checkGate(info upgradetypes.Plan, callback callbackInfo) bool {
+	if fw.gate == nil {
+		return true
+	}
+
+	enabled, err := fw.gate.query(info)
+	reason := "disabled by the upgrade gate"
+	if err != nil {
+		if fw.gate.failOpen {
+			fw.logger.Error("failed to query the upgrade gate, proceeding", "name", info.Name, "error", err)
+			enabled = true
+		}
+		reason = fmt.Sprintf("upgrade gate unavailable: %s", err)
+	}
+
+	if enabled {
+		if fw.gateHeld {
+			fw.logger.Info("upgrade gate enabled the held upgrade", "name", info.Name)
+		}
+		fw.gateHeld = false
+		return true
+	}
+
+	fw.logger.Info("upgrade held", "name", info.Name, "height", info.Height, "reason", reason)
+	if !fw.gateHeld {
+		fw.gateHeld = true
+		fw.heldCallback = callback
+		callback.Error = reason
+		_ = fw.callbacks.send(CallbackEventUpgradeHeld, callback)
+	}
+
+	return false
+}
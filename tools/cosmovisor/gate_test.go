@@ -0,0 +1,189 @@
+package cosmovisor
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	upgradetypes "cosmossdk.io/x/upgrade/types"
+)
+
+// gateStub is a test upgrade gate answering body, and recording the queries it received.
+type gateStub struct {
+	*httptest.Server
+
+	mu      sync.Mutex
+	body    string
+	queries []url.Values
+}
+
+func newGateStub(t *testing.T, body string) *gateStub {
+	t.Helper()
+
+	g := &gateStub{body: body}
+	g.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		g.mu.Lock()
+		defer g.mu.Unlock()
+
+		g.queries = append(g.queries, req.URL.Query())
+		fmt.Fprint(w, g.body)
+	}))
+	t.Cleanup(g.Close)
+
+	return g
+}
+
+func (g *gateStub) set(body string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.body = body
+}
+
+func (g *gateStub) received() []url.Values {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	return g.queries
+}
+
+func newGatedWatcher(t *testing.T, cfg *Config) *fileWatcher {
+	t.Helper()
+
+	cfg.Home = t.TempDir()
+	cfg.Name = "dummyd"
+	fw := newTestWatcher(t, cfg)
+	fw.getHeight = func() (int64, error) { return 49, nil }
+	writeUpgradeInfo(t, cfg, upgradetypes.Plan{Name: "chain2", Height: 49})
+
+	return fw
+}
+
+func TestUpgradeGate(t *testing.T) {
+	srv := newCallbackRecorder(t)
+	gate := newGateStub(t, "false")
+	fw := newGatedWatcher(t, &Config{CallbackAPI: srv.URL, UpgradeGateURL: gate.URL + "/flags/upgrade?env=prod"})
+	held := "/internal/cosmos///" + callbackPaths[CallbackEventUpgradeHeld]
+
+	// a disabled gate holds the upgrade and is queried again on every poll
+	require.False(t, fw.CheckUpdate(upgradetypes.Plan{}))
+	require.False(t, fw.CheckUpdate(upgradetypes.Plan{}))
+	require.Len(t, gate.received(), 2)
+	require.Equal(t, url.Values{"env": {"prod"}, "name": {"chain2"}, "height": {"49"}}, gate.received()[0])
+	got := srv.received(held)
+	require.Len(t, got, 1)
+	require.Equal(t, "chain2", got[0].Name)
+	require.Equal(t, "disabled by the upgrade gate", got[0].Error)
+	require.Len(t, srv.received("/internal/cosmos///"+callbackPaths[CallbackEventReached]), 1)
+
+	gate.set(`{"enabled": true}`)
+	require.True(t, fw.CheckUpdate(upgradetypes.Plan{}))
+	require.Len(t, srv.received(held), 1)
+}
+
+func TestUpgradeGateEnabled(t *testing.T) {
+	srv := newCallbackRecorder(t)
+	gate := newGateStub(t, "true\n")
+	fw := newGatedWatcher(t, &Config{CallbackAPI: srv.URL, UpgradeGateURL: gate.URL})
+
+	require.True(t, fw.CheckUpdate(upgradetypes.Plan{}))
+	require.Len(t, gate.received(), 1)
+	require.Empty(t, srv.received("/internal/cosmos///"+callbackPaths[CallbackEventUpgradeHeld]))
+}
+
+func TestUpgradeGateUnavailable(t *testing.T) {
+	unblock := make(chan struct{})
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		select {
+		case <-req.Context().Done():
+		case <-unblock:
+		}
+	}))
+	defer slow.Close()
+	defer close(unblock)
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer failing.Close()
+
+	cases := map[string]struct {
+		url          string
+		failOpen     bool
+		expectUpdate bool
+	}{
+		"timeout, fail closed": {url: slow.URL, expectUpdate: false},
+		"timeout, fail open":   {url: slow.URL, failOpen: true, expectUpdate: true},
+		"error, fail closed":   {url: failing.URL, expectUpdate: false},
+		"error, fail open":     {url: failing.URL, failOpen: true, expectUpdate: true},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			fw := newGatedWatcher(t, &Config{UpgradeGateURL: tc.url, UpgradeGateTimeout: 10 * time.Millisecond, UpgradeGateFailOpen: tc.failOpen})
+			require.Equal(t, tc.expectUpdate, fw.CheckUpdate(upgradetypes.Plan{}))
+			require.Equal(t, !tc.expectUpdate, fw.gateHeld)
+		})
+	}
+}
+
+func TestUpgradeGateAfterExit(t *testing.T) {
+	cases := map[string]string{
+		"disabled":        "false",
+		"failing, closed": "not a boolean",
+	}
+
+	for name, body := range cases {
+		t.Run(name, func(t *testing.T) {
+			gate := newGateStub(t, body)
+			fw := newGatedWatcher(t, &Config{UpgradeGateURL: gate.URL})
+
+			// the app exits while the upgrade is held, it goes through once the gate enables it
+			polls := 0
+			fw.sleep = func(time.Duration) {
+				polls++
+				if polls == 2 {
+					gate.set("true")
+				}
+			}
+			require.True(t, fw.checkAfterExit(upgradetypes.Plan{}))
+			require.Equal(t, 2, polls)
+			require.Len(t, gate.received(), 3)
+			require.False(t, fw.gateHeld)
+		})
+	}
+}
+
+func TestParseGateResponse(t *testing.T) {
+	cases := map[string]struct {
+		body      string
+		expect    bool
+		expectErr bool
+	}{
+		"plain true":      {body: "true", expect: true},
+		"plain one":       {body: " 1\n", expect: true},
+		"plain false":     {body: "FALSE", expect: false},
+		"json enabled":    {body: `{"enabled":true,"flag":"upgrade"}`, expect: true},
+		"json disabled":   {body: `{"enabled":false}`, expect: false},
+		"json no enabled": {body: `{"flag":"upgrade"}`, expectErr: true},
+		"empty":           {body: "", expectErr: true},
+		"garbage":         {body: "maybe", expectErr: true},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			enabled, err := parseGateResponse([]byte(tc.body))
+			if tc.expectErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.expect, enabled)
+		})
+	}
+}
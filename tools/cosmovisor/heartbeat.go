@@ -0,0 +1,22 @@
+package cosmovisor
+
+// heartbeat sends a heartbeat callback, carrying the tracked upgrade and the last known height,
+// if heartbeatInterval elapsed since the last one. It is called on every poll of the monitoring
+// loop, so the callback API can tell a live watcher from a dead one.
+func (fw *fileWatcher) heartbeat() {
+	if fw.heartbeatInterval <= 0 {
+		return
+	}
+
+	now := fw.now()
+	if !fw.heartbeatSent.IsZero() && now.Sub(fw.heartbeatSent) < fw.heartbeatInterval {
+		return
+	}
+	fw.heartbeatSent = now
+
+	_ = fw.callbacks.send(CallbackEventHeartbeat, callbackInfo{
+		Name:          fw.currentInfo.Name,
+		Height:        fw.currentInfo.Height,
+		CurrentHeight: fw.lastHeight,
+	})
+}
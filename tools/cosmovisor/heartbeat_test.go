@@ -0,0 +1,57 @@
+package cosmovisor
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	upgradetypes "cosmossdk.io/x/upgrade/types"
+)
+
+func TestHeartbeat(t *testing.T) {
+	require := require.New(t)
+	srv := newCallbackRecorder(t)
+	cfg := &Config{Home: t.TempDir(), Name: "dummyd", CallbackAPI: srv.URL, CallbackHeartbeat: time.Minute}
+	fw := newTestWatcher(t, cfg)
+	heartbeat := "/internal/cosmos///" + callbackPaths[CallbackEventHeartbeat]
+
+	now := time.Unix(0, 0)
+	fw.now = func() time.Time { return now }
+	fw.currentInfo = upgradetypes.Plan{Name: "chain2", Height: 100}
+	fw.lastHeight = 40
+
+	// the first poll sends a heartbeat, the next ones wait for the interval
+	fw.heartbeat()
+	now = now.Add(59 * time.Second)
+	fw.heartbeat()
+	got := srv.received(heartbeat)
+	require.Len(got, 1)
+	require.Equal(callbackInfo{Event: CallbackEventHeartbeat, Name: "chain2", Height: 100, CurrentHeight: 40}, got[0])
+
+	now = now.Add(time.Second)
+	fw.lastHeight = 52
+	fw.heartbeat()
+	got = srv.received(heartbeat)
+	require.Len(got, 2)
+	require.Equal(int64(52), got[1].CurrentHeight)
+}
+
+func TestHeartbeatDisabled(t *testing.T) {
+	srv := newCallbackRecorder(t)
+	fw := newTestWatcher(t, &Config{Home: t.TempDir(), Name: "dummyd", CallbackAPI: srv.URL})
+	fw.heartbeat()
+	require.Empty(t, srv.received("/internal/cosmos///"+callbackPaths[CallbackEventHeartbeat]))
+}
+
+func TestMonitorUpdateHeartbeat(t *testing.T) {
+	srv := newCallbackRecorder(t)
+	cfg := &Config{Home: t.TempDir(), Name: "dummyd", CallbackAPI: srv.URL, CallbackHeartbeat: time.Nanosecond}
+	fw := newTestWatcher(t, cfg)
+	defer fw.Stop()
+
+	fw.MonitorUpdate(upgradetypes.Plan{})
+	require.Eventually(t, func() bool {
+		return len(srv.received("/internal/cosmos///"+callbackPaths[CallbackEventHeartbeat])) >= 2
+	}, time.Second, time.Millisecond)
+}
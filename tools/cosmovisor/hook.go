@@ -0,0 +1,85 @@
+package cosmovisor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"time"
+
+	upgradetypes "cosmossdk.io/x/upgrade/types"
+)
+
+// defaultHookTimeout is used when the post height reached hook is configured without a timeout.
+const defaultHookTimeout = time.Minute
+
+// runPostHeightReachedHook runs the post height reached hook, if one is configured, with the
+// arguments [ upgrade.Name, upgrade.Height ] and the upgrade metadata in COSMOVISOR_UPGRADE_* env vars.
+// Like COSMOVISOR_CUSTOM_PREUPGRADE, it runs from the home directory and is made executable for
+// the current user. The hook is killed once the timeout elapses. When the hook fails, the error is
+// logged, a hook_failed callback is emitted and the error is returned.
+func (fw *fileWatcher) runPostHeightReachedHook(info upgradetypes.Plan, callback callbackInfo) error {
+	if fw.postHook == "" {
+		return nil
+	}
+
+	result, err := fw.execPostHeightReachedHook(info, callback)
+	if err != nil {
+		fw.logger.Error("COSMOVISOR_POST_HEIGHT_REACHED_HOOK failed", "command", fw.postHook, "error", err, "result", string(result))
+		callback.Error = err.Error()
+		_ = fw.callbacks.send(CallbackEventHookFailed, callback)
+		return err
+	}
+
+	fw.logger.Info("COSMOVISOR_POST_HEIGHT_REACHED_HOOK result", "command", fw.postHook, "argv1", info.Name, "argv2", fmt.Sprintf("%d", info.Height), "result", string(result))
+	return nil
+}
+
+func (fw *fileWatcher) execPostHeightReachedHook(info upgradetypes.Plan, callback callbackInfo) ([]byte, error) {
+	stat, err := os.Stat(fw.postHook)
+	if err != nil {
+		return nil, err
+	}
+	if !stat.Mode().IsRegular() {
+		_, f := filepath.Split(fw.postHook)
+		return nil, fmt.Errorf("%s is not a regular file", f)
+	}
+
+	// Set the execute bit for only the current user
+	oldMode := stat.Mode().Perm()
+	newMode := oldMode | 0o100
+	if oldMode != newMode {
+		if err := os.Chmod(fw.postHook, newMode); err != nil {
+			return nil, fmt.Errorf("could not add execute permission: %w", err)
+		}
+	}
+
+	timeout := fw.postHookTimeout
+	if timeout <= 0 {
+		timeout = defaultHookTimeout
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, fw.postHook, info.Name, fmt.Sprintf("%d", info.Height))
+	cmd.Dir = fw.postHookDir
+	cmd.Env = append(os.Environ(),
+		"COSMOVISOR_UPGRADE_NAME="+info.Name,
+		fmt.Sprintf("COSMOVISOR_UPGRADE_HEIGHT=%d", info.Height),
+		"COSMOVISOR_UPGRADE_INFO="+info.Info,
+		"COSMOVISOR_UPGRADE_VERSION="+callback.Version,
+		"COSMOVISOR_UPGRADE_REPO="+callback.Repo,
+	)
+	// don't wait on output pipes held open by processes the hook left behind
+	cmd.WaitDelay = time.Second
+
+	result, err := cmd.CombinedOutput()
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return result, fmt.Errorf("timed out after %s", timeout)
+	}
+
+	return result, err
+}
@@ -0,0 +1,113 @@
+package cosmovisor
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	upgradetypes "cosmossdk.io/x/upgrade/types"
+)
+
+func TestPostHeightReachedHook(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("hook scripts are shell scripts")
+	}
+
+	cases := map[string]struct {
+		script        string
+		mode          os.FileMode
+		timeout       time.Duration
+		policy        string
+		expectOutput  string
+		expectErr     string
+		expectUpgrade bool
+	}{
+		"success": {
+			script:        `echo "$1 $2 $COSMOVISOR_UPGRADE_NAME $COSMOVISOR_UPGRADE_HEIGHT $COSMOVISOR_UPGRADE_INFO" > hook.out`,
+			expectOutput:  "chain2 49 chain2 49 some info\n",
+			expectUpgrade: true,
+		},
+		"not executable": {
+			script:        `echo "$1" > hook.out`,
+			mode:          0o600,
+			expectOutput:  "chain2\n",
+			expectUpgrade: true,
+		},
+		"timeout": {
+			script:    "sleep 10",
+			timeout:   50 * time.Millisecond,
+			expectErr: "timed out after 50ms",
+		},
+		"non-zero exit": {
+			script:    "exit 3",
+			expectErr: "exit status 3",
+		},
+		"non-zero exit, continue": {
+			script:        "exit 3",
+			policy:        HookFailureContinue,
+			expectErr:     "exit status 3",
+			expectUpgrade: true,
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			srv := newCallbackRecorder(t)
+			cfg := &Config{
+				Home:                     t.TempDir(),
+				Name:                     "dummyd",
+				CallbackAPI:              srv.URL,
+				PostHeightReachedHook:    "post-hook.sh",
+				PostHeightReachedTimeout: tc.timeout,
+				PostHeightReachedPolicy:  tc.policy,
+			}
+			fw := newTestWatcher(t, cfg)
+			fw.getHeight = func() (int64, error) { return 49, nil }
+			mode := tc.mode
+			if mode == 0 {
+				mode = 0o700
+			}
+			require.NoError(t, os.WriteFile(cfg.PostHeightReachedHookPath(), []byte("#!/bin/sh\n"+tc.script+"\n"), mode))
+			writeUpgradeInfo(t, cfg, upgradetypes.Plan{Name: "chain2", Height: 49, Info: "some info"})
+
+			start := time.Now()
+			require.Equal(t, tc.expectUpgrade, fw.CheckUpdate(upgradetypes.Plan{}))
+			require.Less(t, time.Since(start), 5*time.Second)
+
+			failures := srv.received("/internal/cosmos///" + callbackPaths[CallbackEventHookFailed])
+			if tc.expectErr == "" {
+				require.Empty(t, failures)
+				// the hook is run from the home directory
+				bz, err := os.ReadFile(filepath.Join(cfg.Home, "hook.out"))
+				require.NoError(t, err)
+				require.Equal(t, tc.expectOutput, string(bz))
+				return
+			}
+
+			require.Len(t, failures, 1)
+			require.Equal(t, "chain2", failures[0].Name)
+			require.Equal(t, int64(49), failures[0].Height)
+			require.Contains(t, failures[0].Error, tc.expectErr)
+
+			// an aborted upgrade is not signaled on the next poll either
+			if !tc.expectUpgrade {
+				require.False(t, fw.CheckUpdate(upgradetypes.Plan{}))
+			}
+		})
+	}
+}
+
+func TestPostHeightReachedHookMissing(t *testing.T) {
+	srv := newCallbackRecorder(t)
+	cfg := &Config{Home: t.TempDir(), Name: "dummyd", CallbackAPI: srv.URL, PostHeightReachedHook: "missing.sh"}
+	fw := newTestWatcher(t, cfg)
+	fw.getHeight = func() (int64, error) { return 49, nil }
+	writeUpgradeInfo(t, cfg, upgradetypes.Plan{Name: "chain2", Height: 49})
+
+	require.False(t, fw.CheckUpdate(upgradetypes.Plan{}))
+	require.Len(t, srv.received("/internal/cosmos///"+callbackPaths[CallbackEventHookFailed]), 1)
+}
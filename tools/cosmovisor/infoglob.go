@@ -0,0 +1,117 @@
+package cosmovisor
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	upgradetypes "cosmossdk.io/x/upgrade/types"
+)
+
+// invalidCandidate is a file matching infoGlob which failed to parse.
+type invalidCandidate struct {
+	digest []byte
+	polls  int
+}
+
+// selectUpgradeInfoFile points the watcher to the file holding the next applicable plan among
+// those matching infoGlob: the plan with the lowest height above the last applied upgrade, the
+// first file in lexical order winning a tie. It reports false if no plan is applicable, the
+// watcher keeping the last selected file, which the upgrade it held may still be read from.
+// The files are parsed on every call, so they can be added and removed between polls. Invalid
+// files are skipped, and reported like an invalid upgrade info file.
+func (fw *fileWatcher) selectUpgradeInfoFile(currentUpgrade upgradetypes.Plan) bool {
+	matches, err := filepath.Glob(fw.infoGlob)
+	if err != nil {
+		fw.logger.Error("invalid upgrade info glob", "pattern", fw.infoGlob, "error", err)
+		return false
+	}
+
+	if currentUpgrade.Name == "" {
+		currentUpgrade = fw.readCurrentUpgrade()
+	}
+
+	var selected string
+	var next upgradetypes.Plan
+	invalid := make(map[string]*invalidCandidate)
+	for _, file := range matches {
+		if stat, err := os.Stat(file); err != nil || !stat.Mode().IsRegular() {
+			continue
+		}
+
+		plan, err := ParseUpgradeInfoFile(file, fw.parseOpts...)
+		if err != nil {
+			if candidate := fw.handleInvalidCandidate(file, err); candidate != nil {
+				invalid[file] = candidate
+			}
+			continue
+		}
+		if strings.EqualFold(plan.Name, currentUpgrade.Name) || plan.Height <= currentUpgrade.Height {
+			continue
+		}
+		if selected == "" || plan.Height < next.Height {
+			selected, next = file, plan
+		}
+	}
+
+	// the files which were removed, or became valid, are forgotten
+	fw.invalidCandidates = invalid
+
+	if selected == "" {
+		return false
+	}
+
+	if selected != fw.filename {
+		fw.logger.Info("watching upgrade info file", "file", selected, "name", next.Name, "height", next.Height)
+		// the newly selected file is read regardless of how it compares to the previous one
+		fw.filename = selected
+		fw.lastModTime = time.Time{}
+		fw.lastDigest = nil
+	}
+
+	return true
+}
+
+// handleInvalidCandidate reports a file matching infoGlob which failed to parse as
+// handleInvalid does: a validation_failed callback is emitted the first time a given content
+// fails and, if a quarantine directory is set, the file is moved there once the same content
+// failed quarantineAfter polls in a row. It returns the state of the file, nil once quarantined.
+func (fw *fileWatcher) handleInvalidCandidate(file string, parseErr error) *invalidCandidate {
+	fw.logger.Error("skipping invalid upgrade info file", "file", file, "error", parseErr)
+	// unlike the watched file, the callbacks must tell which of the candidates is invalid
+	errMsg := fmt.Sprintf("%s: %s", file, parseErr)
+
+	digest, err := fileDigest(file)
+	if err != nil {
+		fw.logger.Error("failed to hash invalid upgrade info file", "file", file, "error", err)
+	}
+
+	candidate, ok := fw.invalidCandidates[file]
+	if !ok || digest == nil || !bytes.Equal(digest, candidate.digest) {
+		candidate = &invalidCandidate{digest: digest}
+		modTime := time.Time{}
+		if stat, err := os.Stat(file); err == nil {
+			modTime = stat.ModTime()
+		}
+		fw.logDecision(decisionRecord{File: file, FileModTime: modTime, CurrentHeight: fw.lastHeight, Reason: reasonInvalidPlan, Error: parseErr.Error()})
+		_ = fw.callbacks.send(CallbackEventValidationFailed, callbackInfo{Error: errMsg})
+	}
+	candidate.polls++
+
+	if fw.quarantineDir == "" || digest == nil || candidate.polls < fw.quarantineThreshold() {
+		return candidate
+	}
+
+	dest, err := fw.quarantine(file, digest)
+	if err != nil {
+		fw.logger.Error("failed to quarantine invalid upgrade info file", "file", file, "error", err)
+		return candidate
+	}
+
+	fw.logger.Error("invalid upgrade info file quarantined", "file", file, "quarantine_path", dest)
+	_ = fw.callbacks.send(CallbackEventQuarantined, callbackInfo{Error: errMsg, QuarantinePath: dest})
+	return nil
+}
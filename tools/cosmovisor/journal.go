@@ -0,0 +1,200 @@
+package cosmovisor
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"cosmossdk.io/log"
+)
+
+// journalIdentifier is the SYSLOG_IDENTIFIER of the journal entries.
+const journalIdentifier = "cosmovisor"
+
+// errJournalUnavailable is returned when the systemd journal cannot be written to on this host.
+var errJournalUnavailable = errors.New("systemd journal not available")
+
+// syslog priorities of the journal entries
+const (
+	journalPriorityErr     = 3
+	journalPriorityWarning = 4
+	journalPriorityNotice  = 5
+	journalPriorityInfo    = 6
+	journalPriorityDebug   = 7
+)
+
+// journalPriorities are the priorities of the events, journalPriorityInfo by default.
+var journalPriorities = map[CallbackEvent]int{
+	CallbackEventReached:          journalPriorityWarning,
+	CallbackEventImminent:         journalPriorityNotice,
+	CallbackEventHeartbeat:        journalPriorityDebug,
+	CallbackEventValidationFailed: journalPriorityErr,
+	CallbackEventHeightOverrun:    journalPriorityErr,
+	CallbackEventHookFailed:       journalPriorityErr,
+	CallbackEventNotConfirmed:     journalPriorityWarning,
+	CallbackEventPlanAmended:      journalPriorityNotice,
+	CallbackEventUpgradeFailed:    journalPriorityErr,
+	CallbackEventInfoUnreadable:   journalPriorityErr,
+	CallbackEventRollbackDetected: journalPriorityWarning,
+	CallbackEventUpgradeHeld:      journalPriorityNotice,
+	CallbackEventQuarantined:      journalPriorityErr,
+	CallbackEventDiskSpaceLow:     journalPriorityErr,
+	CallbackEventCosmovisorTooOld: journalPriorityErr,
+}
+
+// journalSink writes the upgrade events to the systemd journal, with the callback info as
+// COSMOVISOR_* fields. A nil journalSink writes nothing.
+type journalSink struct {
+	logger log.Logger
+	// write sends an entry, encoded in the journal native protocol
+	write func([]byte) error
+}
+
+// newJournalSink returns the journal sink if enabled, nil if disabled or if the journal is not
+// available on this host, e.g. not on Linux or without the journal socket.
+func newJournalSink(cfg *Config, logger log.Logger) *journalSink {
+	if !cfg.JournaldEnabled {
+		return nil
+	}
+
+	write, err := dialJournal()
+	if err != nil {
+		logger.Info("not writing upgrade events to the systemd journal", "error", err)
+		return nil
+	}
+
+	return &journalSink{logger: logger, write: write}
+}
+
+// send writes the event to the journal. Failures are logged, the journal is best effort.
+func (j *journalSink) send(event CallbackEvent, info callbackInfo) {
+	if j == nil {
+		return
+	}
+
+	entry, err := journalEntry(event, info)
+	if err == nil {
+		err = j.write(entry)
+	}
+	if err != nil {
+		j.logger.Error("failed to write upgrade event to the systemd journal", "event", event, "error", err)
+	}
+}
+
+// journalEntry encodes the event in the journal native protocol.
+func journalEntry(event CallbackEvent, info callbackInfo) ([]byte, error) {
+	fields, err := journalFields(event, info)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make([]string, 0, len(fields))
+	for key := range fields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var buf bytes.Buffer
+	for _, key := range keys {
+		val := fields[key]
+		if !strings.Contains(val, "\n") {
+			fmt.Fprintf(&buf, "%s=%s\n", key, val)
+			continue
+		}
+
+		// multi-line values are sent as the field name, their little endian size and the value
+		buf.WriteString(key)
+		buf.WriteByte('\n')
+		_ = binary.Write(&buf, binary.LittleEndian, uint64(len(val)))
+		buf.WriteString(val)
+		buf.WriteByte('\n')
+	}
+
+	return buf.Bytes(), nil
+}
+
+// journalFields are the journal fields of the event: MESSAGE, MESSAGE_ID, PRIORITY and
+// SYSLOG_IDENTIFIER, and the fields of the callback info set prefixed with COSMOVISOR_,
+// e.g. COSMOVISOR_CURRENT_HEIGHT, tags being COSMOVISOR_TAG_*.
+func journalFields(event CallbackEvent, info callbackInfo) (map[string]string, error) {
+	info.Event = event
+	bz, err := json.Marshal(info)
+	if err != nil {
+		return nil, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(bz))
+	dec.UseNumber()
+	var values map[string]interface{}
+	if err := dec.Decode(&values); err != nil {
+		return nil, err
+	}
+
+	priority, ok := journalPriorities[event]
+	if !ok {
+		priority = journalPriorityInfo
+	}
+	fields := map[string]string{
+		"MESSAGE":           journalMessage(event, info),
+		"MESSAGE_ID":        journalMessageID(event),
+		"PRIORITY":          fmt.Sprintf("%d", priority),
+		"SYSLOG_IDENTIFIER": journalIdentifier,
+	}
+	for key, val := range values {
+		switch val := val.(type) {
+		case string:
+			if val != "" {
+				fields[journalFieldName("COSMOVISOR_", key)] = val
+			}
+		case json.Number:
+			if val.String() != "0" {
+				fields[journalFieldName("COSMOVISOR_", key)] = val.String()
+			}
+		case map[string]interface{}:
+			for tag, tagVal := range val {
+				fields[journalFieldName("COSMOVISOR_TAG_", tag)] = fmt.Sprint(tagVal)
+			}
+		}
+	}
+
+	return fields, nil
+}
+
+func journalMessage(event CallbackEvent, info callbackInfo) string {
+	msg := fmt.Sprintf("upgrade %s: %s", event, info.Name)
+	if info.Height > 0 {
+		msg += fmt.Sprintf(" at height %d", info.Height)
+	}
+	if info.Error != "" {
+		msg += ": " + info.Error
+	}
+
+	return msg
+}
+
+// journalMessageID is the 128-bit MESSAGE_ID of the event, stable across releases so the
+// entries of an event can be matched with e.g. journalctl MESSAGE_ID=...
+func journalMessageID(event CallbackEvent) string {
+	digest := sha256.Sum256([]byte("cosmovisor/" + string(event)))
+	return hex.EncodeToString(digest[:16])
+}
+
+// journalFieldName is the journal field name of key, uppercased and with the characters
+// journal field names cannot have replaced by underscores.
+func journalFieldName(prefix, key string) string {
+	return prefix + strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '_':
+			return r
+		case r >= 'a' && r <= 'z':
+			return r - 'a' + 'A'
+		default:
+			return '_'
+		}
+	}, key)
+}
@@ -0,0 +1,29 @@
+//go:build linux
+
+package cosmovisor
+
+import (
+	"fmt"
+	"net"
+	"os"
+)
+
+// journalSocket is the socket the systemd journal receives native protocol entries on.
+const journalSocket = "/run/systemd/journal/socket"
+
+// dialJournal connects to the systemd journal socket.
+func dialJournal() (func([]byte) error, error) {
+	if _, err := os.Stat(journalSocket); err != nil {
+		return nil, fmt.Errorf("%w: %w", errJournalUnavailable, err)
+	}
+
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: journalSocket, Net: "unixgram"})
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", errJournalUnavailable, err)
+	}
+
+	return func(entry []byte) error {
+		_, err := conn.Write(entry)
+		return err
+	}, nil
+}
@@ -0,0 +1,8 @@
+//go:build !linux
+
+package cosmovisor
+
+// dialJournal fails, the systemd journal is Linux only.
+func dialJournal() (func([]byte) error, error) {
+	return nil, errJournalUnavailable
+}
@@ -0,0 +1,82 @@
+package cosmovisor
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"cosmossdk.io/log"
+)
+
+func TestJournalFields(t *testing.T) {
+	fields, err := journalFields(CallbackEventReached, callbackInfo{
+		Name:          "chain2",
+		Height:        49,
+		CurrentHeight: 49,
+		Tags:          map[string]string{"region": "eu-west", "cluster.name": "a"},
+	})
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{
+		"MESSAGE":                     "upgrade reached: chain2 at height 49",
+		"MESSAGE_ID":                  journalMessageID(CallbackEventReached),
+		"PRIORITY":                    "4",
+		"SYSLOG_IDENTIFIER":           "cosmovisor",
+		"COSMOVISOR_EVENT":            "reached",
+		"COSMOVISOR_NAME":             "chain2",
+		"COSMOVISOR_HEIGHT":           "49",
+		"COSMOVISOR_CURRENT_HEIGHT":   "49",
+		"COSMOVISOR_TAG_REGION":       "eu-west",
+		"COSMOVISOR_TAG_CLUSTER_NAME": "a",
+	}, fields)
+
+	fields, err = journalFields(CallbackEventHeartbeat, callbackInfo{})
+	require.NoError(t, err)
+	require.Equal(t, "7", fields["PRIORITY"])
+
+	fields, err = journalFields(CallbackEventDetected, callbackInfo{})
+	require.NoError(t, err)
+	require.Equal(t, "6", fields["PRIORITY"])
+}
+
+func TestJournalMessageID(t *testing.T) {
+	id := journalMessageID(CallbackEventReached)
+	require.Len(t, id, 32)
+	require.Equal(t, id, journalMessageID(CallbackEventReached))
+	require.NotEqual(t, id, journalMessageID(CallbackEventDetected))
+}
+
+func TestJournalEntry(t *testing.T) {
+	entry, err := journalEntry(CallbackEventUpgradeFailed, callbackInfo{Name: "chain2", Error: "exit status 1\npanic"})
+	require.NoError(t, err)
+
+	// multi-line values are length prefixed, the others are KEY=value lines
+	size := make([]byte, 8)
+	binary.LittleEndian.PutUint64(size, uint64(len("exit status 1\npanic")))
+	require.True(t, bytes.Contains(entry, append(append([]byte("COSMOVISOR_ERROR\n"), size...), "exit status 1\npanic\n"...)))
+	require.True(t, bytes.Contains(entry, []byte("\nCOSMOVISOR_NAME=chain2\n")))
+	require.True(t, bytes.Contains(entry, []byte("\nPRIORITY=3\n")))
+}
+
+func TestCallbackJournal(t *testing.T) {
+	var entries [][]byte
+	d := newCallbackDispatcher(&Config{}, log.NewNopLogger(), nil)
+	d.journal = &journalSink{logger: log.NewNopLogger(), write: func(entry []byte) error {
+		entries = append(entries, entry)
+		return nil
+	}}
+
+	// written even though there is no callback endpoint
+	require.NoError(t, d.send(CallbackEventDetected, callbackInfo{Name: "chain2", Height: 49}))
+	require.Len(t, entries, 1)
+	require.True(t, bytes.Contains(entries[0], []byte("COSMOVISOR_EVENT=detected\n")))
+}
+
+func TestNewJournalSinkDisabled(t *testing.T) {
+	require.Nil(t, newJournalSink(&Config{}, log.NewNopLogger()))
+
+	// a nil sink writes nothing
+	var j *journalSink
+	j.send(CallbackEventDetected, callbackInfo{})
+}
@@ -0,0 +1,110 @@
+package cosmovisor
+
+import (
+	"net/http"
+	neturl "net/url"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+
+	"cosmossdk.io/log"
+)
+
+const metricsNamespace = "cosmovisor"
+
+// metrics holds the collectors exposed by the metrics server.
+// All methods are no-ops on a nil receiver, which is used when the metrics server is disabled.
+type metrics struct {
+	registry *prometheus.Registry
+
+	callbackBreakerState *prometheus.GaugeVec
+	callbackInflight     prometheus.Gauge
+	callbackDuration     *prometheus.HistogramVec
+	callbackFailures     *prometheus.CounterVec
+}
+
+func newMetrics() *metrics {
+	m := &metrics{
+		registry: prometheus.NewRegistry(),
+		callbackBreakerState: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: metricsNamespace,
+			Name:      "callback_breaker_state",
+			Help:      "State of the callback circuit breaker of a callback host (0 = closed, 1 = open, 2 = half-open).",
+		}, []string{"endpoint"}),
+		callbackInflight: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: metricsNamespace,
+			Name:      "callback_inflight",
+			Help:      "Number of callbacks currently being sent.",
+		}),
+		callbackDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: metricsNamespace,
+			Name:      "callback_duration_seconds",
+			Help:      "Round-trip latency of the callbacks sent, successful or not.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"event", "endpoint"}),
+		callbackFailures: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "callback_failures_total",
+			Help:      "Number of callbacks that failed to be delivered.",
+		}, []string{"event", "endpoint"}),
+	}
+
+	m.registry.MustRegister(m.callbackBreakerState, m.callbackInflight, m.callbackDuration, m.callbackFailures)
+	return m
+}
+
+func (m *metrics) setBreakerState(endpoint string, state breakerState) {
+	if m == nil {
+		return
+	}
+
+	m.callbackBreakerState.WithLabelValues(endpoint).Set(float64(state))
+}
+
+func (m *metrics) addInflight(delta float64) {
+	if m == nil {
+		return
+	}
+
+	m.callbackInflight.Add(delta)
+}
+
+// observeCallback records the latency and outcome of a callback sent to url. Callbacks are not
+// retried, a failure is final.
+func (m *metrics) observeCallback(event CallbackEvent, url string, elapsed time.Duration, err error) {
+	if m == nil {
+		return
+	}
+
+	endpoint := endpointLabel(url)
+	m.callbackDuration.WithLabelValues(string(event), endpoint).Observe(elapsed.Seconds())
+	if err != nil {
+		m.callbackFailures.WithLabelValues(string(event), endpoint).Inc()
+	}
+}
+
+// endpointLabel strips the credentials and query, which may hold secrets, from a callback URL.
+func endpointLabel(rawURL string) string {
+	u, err := neturl.Parse(rawURL)
+	if err != nil {
+		return "invalid"
+	}
+
+	return (&neturl.URL{Scheme: u.Scheme, Host: u.Host, Path: u.Path}).String()
+}
+
+// serveMetrics serves the metrics on addr, along with the given extra routes.
+// It blocks until the server fails.
+func (m *metrics) serveMetrics(addr string, logger log.Logger, routes map[string]http.Handler) {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{}))
+	for pattern, handler := range routes {
+		mux.Handle(pattern, handler)
+	}
+
+	logger.Info("starting metrics server", "addr", addr)
+	if err := http.ListenAndServe(addr, mux); err != nil { //nolint:gosec // the metrics server is for local scraping only
+		logger.Error("metrics server stopped", "error", err)
+	}
+}
@@ -0,0 +1,77 @@
+package cosmovisor
+
+import (
+	"encoding/json"
+	"fmt"
+	"runtime/debug"
+	"strings"
+
+	"github.com/hashicorp/go-version"
+
+	upgradetypes "cosmossdk.io/x/upgrade/types"
+)
+
+// buildVersion returns the version cosmovisor was built at, empty for a development build.
+func buildVersion() string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok || info.Main.Version == "(devel)" {
+		return ""
+	}
+
+	return strings.TrimSpace(info.Main.Version)
+}
+
+// minCosmovisorVersion returns the min_cosmovisor_version field of the plan info, empty if the info
+// is not a JSON object or does not set it. Plan infos given as a URL are not downloaded for it.
+func minCosmovisorVersion(info string) string {
+	var planInfo struct {
+		MinCosmovisorVersion string `json:"min_cosmovisor_version"`
+	}
+	if err := json.Unmarshal([]byte(strings.TrimSpace(info)), &planInfo); err != nil {
+		return ""
+	}
+
+	return strings.TrimSpace(planInfo.MinCosmovisorVersion)
+}
+
+// checkCosmovisorVersion reports whether this cosmovisor is recent enough for the plan, which may
+// require a minimum version with the min_cosmovisor_version field of its info. Otherwise a
+// cosmovisor_upgrade_required callback is emitted with the required and current versions. The
+// check is skipped, logging why, for a development build.
+func (fw *fileWatcher) checkCosmovisorVersion(info upgradetypes.Plan) bool {
+	required := minCosmovisorVersion(info.Info)
+	if required == "" {
+		return true
+	}
+	if fw.cosmovisorVersion == "" {
+		fw.logger.Info("skipping the min cosmovisor version check of a development build", "name", info.Name, "required", required)
+		return true
+	}
+
+	callback := callbackInfo{
+		Name:                      info.Name,
+		Info:                      info.Info,
+		Height:                    info.Height,
+		RequiredCosmovisorVersion: required,
+		CosmovisorVersion:         fw.cosmovisorVersion,
+	}
+
+	current, err := version.NewVersion(fw.cosmovisorVersion)
+	if err != nil {
+		fw.logger.Error("skipping the min cosmovisor version check, invalid cosmovisor version", "version", fw.cosmovisorVersion, "error", err)
+		return true
+	}
+	// a requirement this cosmovisor can't even read is most likely for a later one
+	minVersion, err := version.NewVersion(required)
+	if err != nil {
+		callback.Error = fmt.Sprintf("invalid min_cosmovisor_version %q: %s", required, err)
+	} else if current.LessThan(minVersion) {
+		callback.Error = fmt.Sprintf("cosmovisor %s is older than the required %s", fw.cosmovisorVersion, required)
+	} else {
+		return true
+	}
+
+	fw.logger.Error("cosmovisor is too old for the upgrade plan, ignoring it", "name", info.Name, "required", required, "version", fw.cosmovisorVersion, "error", callback.Error)
+	_ = fw.callbacks.send(CallbackEventCosmovisorTooOld, callback)
+	return false
+}
@@ -0,0 +1,67 @@
+package cosmovisor
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	upgradetypes "cosmossdk.io/x/upgrade/types"
+)
+
+func TestCheckUpdateMinCosmovisorVersion(t *testing.T) {
+	cases := map[string]struct {
+		version      string
+		required     string
+		expectUpdate bool
+		expectError  string
+	}{
+		"no requirement":        {version: "v1.5.0", required: "", expectUpdate: true},
+		"same version":          {version: "v1.5.0", required: "v1.5.0", expectUpdate: true},
+		"newer version":         {version: "v1.6.1", required: "1.6.0", expectUpdate: true},
+		"older version":         {version: "v1.5.0", required: "v1.6.0", expectError: "cosmovisor v1.5.0 is older than the required v1.6.0"},
+		"older prerelease":      {version: "v1.6.0-rc1", required: "v1.6.0", expectError: "cosmovisor v1.6.0-rc1 is older than the required v1.6.0"},
+		"invalid requirement":   {version: "v1.5.0", required: "latest", expectError: `invalid min_cosmovisor_version "latest"`},
+		"development build":     {version: "", required: "v9.0.0", expectUpdate: true},
+		"invalid build version": {version: "devel-abc", required: "v1.6.0", expectUpdate: true},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			srv := newCallbackRecorder(t)
+			cfg := &Config{Home: t.TempDir(), Name: "dummyd", CallbackAPI: srv.URL}
+			fw := newTestWatcher(t, cfg)
+			fw.getHeight = func() (int64, error) { return 49, nil }
+			fw.cosmovisorVersion = tc.version
+			tooOld := "/internal/cosmos///" + callbackPaths[CallbackEventCosmovisorTooOld]
+
+			info := `{"binaries":{"any":"https://github.com/cosmos/gaia/releases/download/v2.0.0/gaiad"}}`
+			if tc.required != "" {
+				info = `{"min_cosmovisor_version":"` + tc.required + `","binaries":{"any":"https://github.com/cosmos/gaia/releases/download/v2.0.0/gaiad"}}`
+			}
+			writeUpgradeInfo(t, cfg, upgradetypes.Plan{Name: "chain2", Height: 49, Info: info})
+			require.Equal(t, tc.expectUpdate, fw.CheckUpdate(upgradetypes.Plan{}))
+
+			got := srv.received(tooOld)
+			if tc.expectUpdate {
+				require.Empty(t, got)
+				return
+			}
+			require.Len(t, got, 1)
+			require.Equal(t, "chain2", got[0].Name)
+			require.Equal(t, tc.required, got[0].RequiredCosmovisorVersion)
+			require.Equal(t, tc.version, got[0].CosmovisorVersion)
+			require.Contains(t, got[0].Error, tc.expectError)
+			// the plan is not acted upon, nor reported again, until it changes
+			require.Empty(t, srv.received("/internal/cosmos///"+callbackPaths[CallbackEventDetected]))
+			require.False(t, fw.CheckUpdate(upgradetypes.Plan{}))
+			require.Len(t, srv.received(tooOld), 1)
+		})
+	}
+}
+
+func TestMinCosmovisorVersion(t *testing.T) {
+	require.Equal(t, "v1.6.0", minCosmovisorVersion(` {"min_cosmovisor_version": " v1.6.0 ", "binaries": {}}`))
+	require.Empty(t, minCosmovisorVersion(`{"binaries": {}}`))
+	require.Empty(t, minCosmovisorVersion("https://example.com/info.json"))
+	require.Empty(t, minCosmovisorVersion(""))
+}
@@ -0,0 +1,87 @@
+package cosmovisor
+
+import (
+	"fmt"
+	"os"
+
+	"cosmossdk.io/log"
+	upgradetypes "cosmossdk.io/x/upgrade/types"
+)
+
+// UpgradePreview describes what cosmovisor would do for the upgrade plan in upgrade-info.json.
+type UpgradePreview struct {
+	// File is the upgrade-info file the plan was read from.
+	File string
+	Plan upgradetypes.Plan
+	// Platforms are the platforms a binary is looked for, in order of preference.
+	Platforms []string
+	// BinariesErr is set when the plan info doesn't list binaries.
+	BinariesErr error
+	// Platform is the platform of the selected binary, empty if no binary matches.
+	Platform string
+	Binary   BinaryRef
+	Version  string
+	Repo     string
+	// UpgradeBin is where the upgrade binary is placed, Installed reports whether it already exists.
+	UpgradeBin string
+	Installed  bool
+}
+
+// PreviewUpgrade reads upgrade-info.json, or the file selected among Config.UpgradeInfoGlob like
+// the watcher does, and resolves the binary selected for the current platform, without
+// triggering anything.
+func PreviewUpgrade(cfg *Config) (UpgradePreview, error) {
+	schema, err := loadUpgradeInfoSchema(cfg.UpgradeInfoSchemaPath)
+	if err != nil {
+		return UpgradePreview{}, err
+	}
+
+	parseOpts := append(cfg.UpgradeInfoParseOptions(), ParseOptionSchema(schema))
+	file := cfg.UpgradeInfoFilePath()
+	if pattern := cfg.UpgradeInfoGlobPattern(); pattern != "" {
+		fw := &fileWatcher{
+			logger:             log.NewNopLogger(),
+			infoGlob:           pattern,
+			currentUpgradeFile: cfg.CurrentUpgradeFilePath(),
+			parseOpts:          parseOpts,
+			// the invalid files are not reported by a preview
+			callbacks: newCallbackDispatcher(&Config{}, log.NewNopLogger(), nil),
+		}
+		if !fw.selectUpgradeInfoFile(upgradetypes.Plan{}) {
+			return UpgradePreview{}, fmt.Errorf("no upgrade info file matching %s holds an upcoming plan: %w", pattern, os.ErrNotExist)
+		}
+		file = fw.filename
+	}
+
+	p, err := ParseUpgradeInfoFile(file, parseOpts...)
+	if err != nil {
+		return UpgradePreview{}, err
+	}
+
+	preview := UpgradePreview{
+		File:       file,
+		Plan:       p,
+		Platforms:  PlatformOrder(cfg.PlatformPreference),
+		UpgradeBin: cfg.UpgradeBin(p.Name),
+	}
+	_, err = os.Stat(preview.UpgradeBin)
+	preview.Installed = err == nil
+
+	binaries, err := ParseUpgradeBinaries(p.Info)
+	if err != nil {
+		// not an error: the upgrade binary can be added manually
+		preview.BinariesErr = err
+		return preview, nil
+	}
+
+	for _, platform := range preview.Platforms {
+		if ref, ok := binaries[platform]; ok {
+			preview.Platform = platform
+			preview.Binary = ref
+			preview.Repo, preview.Version = getVersionAndRepoFromUrl(ref.URL)
+			break
+		}
+	}
+
+	return preview, nil
+}
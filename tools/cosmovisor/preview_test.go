@@ -0,0 +1,78 @@
+package cosmovisor
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	upgradetypes "cosmossdk.io/x/upgrade/types"
+)
+
+func TestPreviewUpgrade(t *testing.T) {
+	cfg := &Config{Home: t.TempDir(), Name: "dummyd", PlatformPreference: []string{"linux/arm64", "any"}}
+	require.NoError(t, os.MkdirAll(filepath.Dir(cfg.UpgradeInfoFilePath()), 0o755))
+
+	writeUpgradeInfo(t, cfg, upgradetypes.Plan{Name: "Chain2", Height: 49, Info: `{"binaries":{
+		"linux/amd64":"https://github.com/org/chain/releases/download/v2.0.0/chaind-amd64",
+		"any":"https://github.com/org/chain/releases/download/v2.0.0/chaind?checksum=sha256:abcd"
+	}}`})
+
+	preview, err := PreviewUpgrade(cfg)
+	require.NoError(t, err)
+	require.Equal(t, "chain2", preview.Plan.Name)
+	require.Equal(t, "any", preview.Platform)
+	require.Equal(t, BinaryRef{URL: "https://github.com/org/chain/releases/download/v2.0.0/chaind", Checksum: "sha256:abcd"}, preview.Binary)
+	require.Equal(t, "v2.0.0", preview.Version)
+	require.Equal(t, "https://github.com/org/chain", preview.Repo)
+	require.Equal(t, cfg.UpgradeBin("chain2"), preview.UpgradeBin)
+	require.False(t, preview.Installed)
+
+	// no binary matches the platform
+	cfg.PlatformPreference = []string{"darwin/arm64"}
+	preview, err = PreviewUpgrade(cfg)
+	require.NoError(t, err)
+	require.Empty(t, preview.Platform)
+	require.NoError(t, preview.BinariesErr)
+
+	// no binaries listed
+	writeUpgradeInfo(t, cfg, upgradetypes.Plan{Name: "chain2", Height: 49})
+	preview, err = PreviewUpgrade(cfg)
+	require.NoError(t, err)
+	require.Error(t, preview.BinariesErr)
+}
+
+func TestPreviewUpgradeMissingFile(t *testing.T) {
+	_, err := PreviewUpgrade(&Config{Home: t.TempDir(), Name: "dummyd"})
+	require.ErrorIs(t, err, os.ErrNotExist)
+}
+
+func TestPreviewUpgradeGlob(t *testing.T) {
+	cfg := &Config{Home: t.TempDir(), Name: "dummyd", UpgradeInfoGlob: "upgrade-info-*.json"}
+	dir := filepath.Dir(cfg.UpgradeInfoFilePath())
+	require.NoError(t, os.MkdirAll(dir, 0o755))
+
+	_, err := PreviewUpgrade(cfg)
+	require.ErrorIs(t, err, os.ErrNotExist)
+
+	// the watched file is ignored, the nearest upcoming plan among the matching files is selected
+	writeUpgradeInfo(t, cfg, upgradetypes.Plan{Name: "chain9", Height: 9})
+	for _, plan := range []upgradetypes.Plan{{Name: "chain3", Height: 300}, {Name: "chain2", Height: 200}} {
+		bz, err := json.Marshal(plan)
+		require.NoError(t, err)
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "upgrade-info-"+plan.Name+".json"), bz, 0o600))
+	}
+
+	preview, err := PreviewUpgrade(cfg)
+	require.NoError(t, err)
+	require.Equal(t, filepath.Join(dir, "upgrade-info-chain2.json"), preview.File)
+	require.Equal(t, "chain2", preview.Plan.Name)
+
+	// a plan already applied is skipped
+	writeCurrentUpgrade(t, cfg, upgradetypes.Plan{Name: "chain2", Height: 200})
+	preview, err = PreviewUpgrade(cfg)
+	require.NoError(t, err)
+	require.Equal(t, "chain3", preview.Plan.Name)
+}
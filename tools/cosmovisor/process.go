@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"net/http"
 	"os"
 	"os/exec"
 	"os/signal"
@@ -32,6 +33,14 @@ func NewLauncher(logger log.Logger, cfg *Config) (Launcher, error) {
 		return Launcher{}, err
 	}
 
+	if fw.metrics != nil {
+		routes := make(map[string]http.Handler)
+		if fw.confirmation != nil {
+			routes["/confirm"] = fw.confirmation
+		}
+		go fw.metrics.serveMetrics(cfg.MetricsAddr, logger, routes)
+	}
+
 	return Launcher{logger: logger, cfg: cfg, fw: fw}, nil
 }
 
@@ -99,7 +108,8 @@ func (l Launcher) Run(args []string, stdout, stderr io.Writer) (bool, error) {
 // When it returns, the process (app) is finished.
 //
 // It returns (true, nil) if an upgrade should be initiated (and we killed the process)
-// It returns (false, err) if the process died by itself
+// It returns (false, err) if the process died by itself, a pending upgrade (e.g. awaiting its
+// confirmation) being waited for first
 // It returns (false, nil) if the process exited normally without triggering an upgrade. This is very unlikely
 // to happen with "start" but may happen with short-lived commands like `simd export ...`
 func (l Launcher) WaitForUpgradeOrExit(cmd *exec.Cmd) (bool, error) {
@@ -146,14 +156,17 @@ func (l Launcher) WaitForUpgradeOrExit(cmd *exec.Cmd) (bool, error) {
 			_ = cmd.Process.Kill()
 		}
 	case err := <-cmdDone:
-		l.fw.Stop()
+		l.fw.stopMonitor()
 		// no error -> command exits normally (eg. short command like `gaiad version`)
 		if err == nil {
+			l.fw.sendStopped(nil)
 			return false, nil
 		}
 		// the app x/upgrade causes a panic and the app can die before the filwatcher finds the
-		// update, so we need to recheck update-info file.
-		if !l.fw.CheckUpdate(currentUpgrade) {
+		// update, so we need to recheck update-info file, waiting for a pending upgrade.
+		// The watcher is only reported stopped when no upgrade follows.
+		if !l.fw.checkAfterExit(currentUpgrade) {
+			l.fw.sendStopped(err)
 			return false, err
 		}
 	}
@@ -165,7 +178,7 @@ func (l Launcher) doBackup() error {
 	if !l.cfg.UnsafeSkipBackup {
 		// check if upgrade-info.json is not empty.
 		var uInfo upgradetypes.Plan
-		upgradeInfoFile, err := os.ReadFile(l.cfg.UpgradeInfoFilePath())
+		upgradeInfoFile, err := os.ReadFile(l.fw.filename)
 		if err != nil {
 			return fmt.Errorf("error while reading upgrade-info.json: %w", err)
 		}
@@ -206,7 +219,7 @@ func (l Launcher) doCustomPreUpgrade() error {
 
 	// check if upgrade-info.json is not empty.
 	var upgradePlan upgradetypes.Plan
-	upgradeInfoFile, err := os.ReadFile(l.cfg.UpgradeInfoFilePath())
+	upgradeInfoFile, err := os.ReadFile(l.fw.filename)
 	if err != nil {
 		return fmt.Errorf("error while reading upgrade-info.json: %w", err)
 	}
@@ -0,0 +1,181 @@
+package cosmovisor
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	upgradetypes "cosmossdk.io/x/upgrade/types"
+)
+
+// defaultPendingInterval is used when Config.PendingInterval is not set.
+const defaultPendingInterval = time.Minute
+
+// reasonHeightNotReached is the reason logged while the node is below the upgrade height.
+const reasonHeightNotReached = "height_not_reached"
+
+// heightSample is a block height observed at a point in time.
+type heightSample struct {
+	height int64
+	at     time.Time
+}
+
+// upgradeProgress describes how far the node is from the upgrade height.
+type upgradeProgress struct {
+	BlocksRemaining int64
+	// BlockTime is the observed average block time, 0 if unknown.
+	BlockTime time.Duration
+	// ETA is the estimated time until the upgrade height is reached, 0 if unknown.
+	ETA time.Duration
+}
+
+// estimateProgress computes the progress towards target from two height samples, prev being
+// the older one. The block time and ETA are unknown until the height has advanced between
+// the samples.
+func estimateProgress(prev, cur heightSample, target int64) upgradeProgress {
+	p := upgradeProgress{BlocksRemaining: target - cur.height}
+	if p.BlocksRemaining < 0 {
+		p.BlocksRemaining = 0
+	}
+
+	blocks := cur.height - prev.height
+	elapsed := cur.at.Sub(prev.at)
+	if prev.height <= 0 || blocks <= 0 || elapsed <= 0 {
+		return p
+	}
+
+	p.BlockTime = elapsed / time.Duration(blocks)
+	p.ETA = p.BlockTime * time.Duration(p.BlocksRemaining)
+	return p
+}
+
+// reportPending reports the progress towards the upgrade height of info, at most once per
+// pendingInterval, through the logs and, if enabled, an upgrade_pending callback.
+func (fw *fileWatcher) reportPending(info upgradetypes.Plan, callback callbackInfo, currentHeight int64) {
+	now := fw.now()
+	cur := heightSample{height: currentHeight, at: now}
+
+	// the block rate is measured between the samples of two consecutive reports rather than
+	// between two polls, in between which the height has most likely not changed. The first
+	// report waits for an interval so there is a rate to report.
+	if fw.pendingTarget != info.Height || currentHeight < fw.pendingSample.height {
+		fw.pendingTarget = info.Height
+		fw.pendingSample = cur
+		fw.pendingReported = now
+		return
+	}
+
+	interval := fw.pendingInterval
+	if interval <= 0 {
+		interval = defaultPendingInterval
+	}
+	if now.Sub(fw.pendingReported) < interval {
+		return
+	}
+	progress := estimateProgress(fw.pendingSample, cur, info.Height)
+	fw.pendingSample = cur
+	fw.pendingReported = now
+
+	fw.logger.Info("upgrade pending",
+		"reason", reasonHeightNotReached,
+		"name", info.Name,
+		"height", info.Height,
+		"current_height", currentHeight,
+		"blocks_remaining", progress.BlocksRemaining,
+		"block_time", progress.BlockTime,
+		"eta", progress.ETA,
+	)
+
+	if fw.notifyPending {
+		callback.CurrentHeight = currentHeight
+		callback.BlocksRemaining = progress.BlocksRemaining
+		callback.ETASeconds = int64(progress.ETA.Seconds())
+		callback.Reason = reasonHeightNotReached
+		_ = fw.callbacks.send(CallbackEventUpgradePending, callback)
+	}
+}
+
+// fireMilestones sends a milestone callback for every offset of heightMilestones the node came
+// within of the upgrade height of info, nearest last. Each milestone fires once per plan, the
+// milestones fired being persisted in the state file so they are not fired again after a restart.
+func (fw *fileWatcher) fireMilestones(info upgradetypes.Plan, callback callbackInfo, currentHeight int64) {
+	if len(fw.heightMilestones) == 0 {
+		return
+	}
+
+	milestones := fw.state.Milestones
+	if milestones == nil || milestones.Name != info.Name || milestones.Height != info.Height {
+		milestones = &firedMilestones{Name: info.Name, Height: info.Height}
+	}
+
+	remaining := info.Height - currentHeight
+	for _, offset := range fw.heightMilestones {
+		if remaining > offset || milestones.fired(offset) {
+			continue
+		}
+
+		milestones.Fired = append(milestones.Fired, offset)
+		fw.state.Milestones = milestones
+		if err := saveWatcherState(fw.stateFile, fw.state); err != nil {
+			fw.logger.Error("failed to save the watcher state", "file", fw.stateFile, "error", err)
+		}
+		fw.logger.Info("upgrade milestone reached", "name", info.Name, "height", info.Height, "milestone", offset, "current_height", currentHeight)
+		callback.CurrentHeight = currentHeight
+		callback.Milestone = offset
+		_ = fw.callbacks.send(CallbackEventMilestone, callback)
+	}
+}
+
+// alertImminent sends an imminent callback, once per plan, as the node comes within imminentBlocks
+// of the upgrade height of info.
+func (fw *fileWatcher) alertImminent(info upgradetypes.Plan, callback callbackInfo, currentHeight int64) {
+	if fw.imminentBlocks <= 0 || info.Height-currentHeight > fw.imminentBlocks {
+		return
+	}
+	if fw.imminentSent.Name == info.Name && fw.imminentSent.Height == info.Height {
+		return
+	}
+	fw.imminentSent = info
+
+	fw.logger.Info("upgrade imminent", "name", info.Name, "height", info.Height, "current_height", currentHeight)
+	callback.CurrentHeight = currentHeight
+	callback.BlocksRemaining = info.Height - currentHeight
+	_ = fw.callbacks.send(CallbackEventImminent, callback)
+}
+
+// parseHeightMilestones parses a comma separated list of positive block offsets, returning
+// them sorted from the farthest to the nearest.
+func parseHeightMilestones(input string) ([]int64, error) {
+	var milestones []int64
+	seen := make(map[int64]bool)
+	for _, s := range strings.Split(input, ",") {
+		s = strings.TrimSpace(s)
+		if s == "" {
+			continue
+		}
+
+		offset, err := strconv.ParseInt(s, 10, 64)
+		if err != nil || offset <= 0 {
+			return nil, fmt.Errorf("invalid milestone %q, expected a positive number of blocks", s)
+		}
+		if !seen[offset] {
+			seen[offset] = true
+			milestones = append(milestones, offset)
+		}
+	}
+	sort.Slice(milestones, func(i, j int) bool { return milestones[i] > milestones[j] })
+
+	return milestones, nil
+}
+
+// formatHeightMilestones is the inverse of parseHeightMilestones.
+func formatHeightMilestones(milestones []int64) string {
+	s := make([]string, len(milestones))
+	for i, offset := range milestones {
+		s[i] = strconv.FormatInt(offset, 10)
+	}
+
+	return strings.Join(s, ",")
+}
@@ -0,0 +1,97 @@
+package cosmovisor
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// defaultQuarantineAfter is used when Config.QuarantineAfter is not set.
+const defaultQuarantineAfter = 3
+
+// handleInvalid records a failure to parse the upgrade info file and keeps watching it. A
+// validation_failed callback is emitted the first time a given content fails, and the file is not
+// read again until it changes.
+func (fw *fileWatcher) handleInvalid(parseErr error, modTime time.Time, digest []byte) {
+	fw.logger.Error("invalid upgrade info file, waiting for it to change", "file", fw.filename, "error", parseErr)
+	fw.lastModTime = modTime
+	fw.lastDigest = digest
+
+	content, err := fileDigest(fw.filename)
+	if err != nil {
+		fw.logger.Error("failed to hash invalid upgrade info file", "file", fw.filename, "error", err)
+	}
+	if content == nil || !bytes.Equal(content, fw.invalidDigest) {
+		fw.invalidCount = 0
+		_ = fw.callbacks.send(CallbackEventValidationFailed, callbackInfo{Error: parseErr.Error()})
+	}
+	fw.invalidDigest = content
+	fw.invalidErr = parseErr
+	fw.quarantineInvalid()
+}
+
+// quarantineInvalid counts a poll of the invalid upgrade info file. If a quarantine directory is
+// set, once the same content failed quarantineAfter polls in a row the file is moved there,
+// emitting a quarantined callback, so that the operator is clearly alerted.
+func (fw *fileWatcher) quarantineInvalid() {
+	if fw.invalidDigest == nil {
+		return
+	}
+	fw.invalidCount++
+
+	if fw.quarantineDir == "" || fw.invalidCount < fw.quarantineThreshold() {
+		return
+	}
+
+	dest, err := fw.quarantine(fw.filename, fw.invalidDigest)
+	if err != nil {
+		fw.logger.Error("failed to quarantine invalid upgrade info file", "file", fw.filename, "error", err)
+		return
+	}
+
+	fw.logger.Error("invalid upgrade info file quarantined", "file", fw.filename, "quarantine_path", dest)
+	_ = fw.callbacks.send(CallbackEventQuarantined, callbackInfo{Error: fw.invalidErr.Error(), QuarantinePath: dest})
+	fw.resetInvalid()
+}
+
+// quarantineThreshold returns the number of polls an invalid file is quarantined after.
+func (fw *fileWatcher) quarantineThreshold() int {
+	if fw.quarantineAfter <= 0 {
+		return defaultQuarantineAfter
+	}
+
+	return fw.quarantineAfter
+}
+
+// resetInvalid forgets the failures of the previous upgrade info file content.
+func (fw *fileWatcher) resetInvalid() {
+	fw.invalidDigest = nil
+	fw.invalidErr = nil
+	fw.invalidCount = 0
+}
+
+// quarantine moves the given upgrade info file into the quarantine directory, named after its
+// original name, the time and its digest, and returns its new path.
+func (fw *fileWatcher) quarantine(file string, digest []byte) (string, error) {
+	if err := os.MkdirAll(fw.quarantineDir, 0o755); err != nil {
+		return "", err
+	}
+
+	dest := filepath.Join(fw.quarantineDir, fmt.Sprintf("%s.%s.%x", filepath.Base(file), fw.now().UTC().Format("20060102T150405Z"), digest[:6]))
+	if err := os.Rename(file, dest); err == nil {
+		return dest, nil
+	}
+
+	// the quarantine directory may be on another filesystem
+	bz, err := os.ReadFile(file)
+	if err != nil {
+		return "", err
+	}
+	if err := os.WriteFile(dest, bz, 0o600); err != nil {
+		return "", err
+	}
+
+	return dest, os.Remove(file)
+}
@@ -0,0 +1,78 @@
+package cosmovisor
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	upgradetypes "cosmossdk.io/x/upgrade/types"
+)
+
+func TestCheckUpdateQuarantine(t *testing.T) {
+	require := require.New(t)
+
+	srv := newCallbackRecorder(t)
+	home := t.TempDir()
+	quarantineDir := filepath.Join(home, "quarantine")
+	cfg := &Config{Home: home, Name: "dummyd", CallbackAPI: srv.URL, QuarantineDir: quarantineDir}
+	fw := newTestWatcher(t, cfg)
+	fw.getHeight = func() (int64, error) { return 0, nil }
+	fw.now = func() time.Time { return time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC) }
+	invalid := "/internal/cosmos///" + callbackPaths[CallbackEventValidationFailed]
+	quarantined := "/internal/cosmos///" + callbackPaths[CallbackEventQuarantined]
+
+	// an invalid file is reported once and not read again until it changes
+	require.NoError(os.WriteFile(cfg.UpgradeInfoFilePath(), []byte(`{"name":"chain2"`), 0o600))
+	require.False(fw.CheckUpdate(upgradetypes.Plan{}))
+	require.False(fw.CheckUpdate(upgradetypes.Plan{}))
+	require.Len(srv.received(invalid), 1)
+	require.Empty(srv.received(quarantined))
+	require.FileExists(cfg.UpgradeInfoFilePath())
+
+	// a different invalid content starts over
+	require.NoError(os.WriteFile(cfg.UpgradeInfoFilePath(), []byte(`{"name":"chain2","height":`), 0o600))
+	require.False(fw.CheckUpdate(upgradetypes.Plan{}))
+	require.False(fw.CheckUpdate(upgradetypes.Plan{}))
+	require.Len(srv.received(invalid), 2)
+	require.Empty(srv.received(quarantined))
+
+	// the third failure in a row of the same content moves it aside
+	require.False(fw.CheckUpdate(upgradetypes.Plan{}))
+	require.NoFileExists(cfg.UpgradeInfoFilePath())
+	got := srv.received(quarantined)
+	require.Len(got, 1)
+	require.NotEmpty(got[0].Error)
+	require.Equal(quarantineDir, filepath.Dir(got[0].QuarantinePath))
+	require.Contains(got[0].QuarantinePath, "upgrade-info.json.20240102T030405Z.")
+	bz, err := os.ReadFile(got[0].QuarantinePath)
+	require.NoError(err)
+	require.Equal(`{"name":"chain2","height":`, string(bz))
+
+	// nothing left to re-read
+	require.False(fw.CheckUpdate(upgradetypes.Plan{}))
+	require.Len(srv.received(invalid), 2)
+
+	// a fresh valid file is processed as usual
+	writeUpgradeInfo(t, cfg, upgradetypes.Plan{Name: "chain2", Height: 100})
+	require.True(fw.CheckUpdate(upgradetypes.Plan{}))
+	require.Equal("chain2", fw.currentInfo.Name)
+	require.Len(srv.received(quarantined), 1)
+}
+
+func TestCheckUpdateQuarantineAfter(t *testing.T) {
+	require := require.New(t)
+
+	home := t.TempDir()
+	cfg := &Config{Home: home, Name: "dummyd", QuarantineDir: filepath.Join(home, "quarantine"), QuarantineAfter: 1}
+	fw := newTestWatcher(t, cfg)
+
+	require.NoError(os.WriteFile(cfg.UpgradeInfoFilePath(), []byte("{"), 0o600))
+	require.False(fw.CheckUpdate(upgradetypes.Plan{}))
+	require.NoFileExists(cfg.UpgradeInfoFilePath())
+	entries, err := os.ReadDir(cfg.QuarantineDir)
+	require.NoError(err)
+	require.Len(entries, 1)
+}
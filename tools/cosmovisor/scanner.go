@@ -2,20 +2,23 @@ package cosmovisor
 
 import (
 	"bytes"
+	"context"
+	"crypto/sha256"
 	"encoding/json"
 	"errors"
 	"fmt"
-	"net/http"
+	"io/fs"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"cosmossdk.io/log"
-	"cosmossdk.io/x/upgrade/plan"
 	upgradetypes "cosmossdk.io/x/upgrade/types"
 )
 
@@ -23,23 +26,146 @@ type fileWatcher struct {
 	filename string // full path to a watched file
 	interval time.Duration
 
-	currentBin  string
+	// infoGlob, if set, is the pattern of the upgrade-info files, filename being the one
+	// holding the next applicable plan
+	infoGlob string
+
+	// currentUpgradeFile is the file cosmovisor records the last applied upgrade in.
+	currentUpgradeFile string
+
+	resolveBin  func() (string, error)
 	currentInfo upgradetypes.Plan
 	lastModTime time.Time
-	cancel      chan bool
+	lastDigest  []byte // sha256 of the last acted upon content, when detecting changes by hash
 	ticker      *time.Ticker
+	checking    sync.Mutex // held by MonitorUpdate while checking for an upgrade
 
-	needsUpdate   bool
-	initialized   bool
-	disableRecase bool
-}
+	pauseMu sync.Mutex // guards paused along with the ticker state
+	paused  bool
+
+	monitorMu     sync.Mutex
+	monitorDone   chan struct{} // set while a MonitorUpdate loop is running
+	monitorCancel chan struct{} // closed to stop the running MonitorUpdate loop, nil once closed
+	monitorExited chan struct{} // closed once the running MonitorUpdate loop exited
+
+	needsUpdate bool
+	initialized bool
+	// the restart heuristic verifies again after restartDelay, if positive, that the running
+	// upgrade differs from the plan before upgrading
+	restartDelay time.Duration
+	sleep        func(time.Duration)
+	scanOnStart  bool
+	parseOpts    []ParseOption
+	minFileAge   time.Duration
+	byModTime    bool
+	byHash       bool
+
+	// getHeight returns the current block height of the node, 0 if unknown.
+	getHeight       func() (int64, error)
+	statusTimeout   time.Duration
+	statusArgs      []string // appended to the status command
+	lastHeight      int64    // last known height, 0 if unknown
+	lastDetected    upgradetypes.Plan
+	verifyHeight    bool
+	heightTolerance int64
+
+	// an unknown height is tolerated for heightGracePeriod after startedAt, and then holds the
+	// upgrade if heightFailClosed
+	startedAt         time.Time
+	heightGracePeriod time.Duration
+	heightFailClosed  bool
+	// appExited is set once the app exited, the height no longer advancing past lastHeight
+	appExited bool
+
+	// cosmovisorVersion is the version of this cosmovisor, empty for a development build
+	cosmovisorVersion string
+
+	// getAppVersion returns the version of the running app
+	getAppVersion   func() (string, error)
+	checkAppVersion bool
+
+	// progress towards the upgrade height, reported while it is not reached
+	now             func() time.Time
+	pendingInterval time.Duration
+	pendingTarget   int64
+	pendingSample   heightSample
+	pendingReported time.Time
+	notifyPending   bool
+
+	// heightMilestones are the offsets from the upgrade height firing a milestone callback,
+	// farthest first. The milestones fired are persisted in the state, for the last plan only.
+	heightMilestones []int64
+
+	// an imminent callback is sent once per plan, imminentSent being the last one, as the node
+	// comes within imminentBlocks of the upgrade height, if positive
+	imminentBlocks int64
+	imminentSent   upgradetypes.Plan
+
+	// a heartbeat callback is sent every heartbeatInterval while monitoring, if positive
+	heartbeatInterval time.Duration
+	heartbeatSent     time.Time
+
+	platformPreference []string
+	nameVersions       map[string]VersionRef
+
+	postHook        string
+	postHookDir     string // the directory the hook is run from
+	postHookTimeout time.Duration
+	postHookAbort   bool // whether a failing hook prevents the upgrade
+
+	// confirmation is nil unless upgrades require an operator confirmation.
+	confirmation      *upgradeConfirmation
+	confirmationAbort bool
+	pendingCallback   callbackInfo
 
-type callbackInfo struct {
-	Name    string `json:"name"`
-	Version string `json:"version"`
-	Repo    string `json:"repo"`
-	Info    string `json:"info"`
-	Height  int64  `json:"height"`
+	// gate is nil unless upgrades are gated on an upgrade gate endpoint, gateHeld is set while
+	// it holds the upgrade of currentInfo
+	gate         *upgradeGate
+	gateHeld     bool
+	heldCallback callbackInfo
+
+	// the upgrade of currentInfo is held, diskHeld being set, while one of diskDirs has less than
+	// minFreeDisk bytes available, if positive. diskAlerted is the last plan low disk space was
+	// reported for before its height.
+	minFreeDisk int64
+	diskDirs    []string
+	diskFree    func(path string) (uint64, error)
+	diskHeld    bool
+	diskAlerted upgradetypes.Plan
+
+	// the upgrade of currentInfo is held, intervalHeld being set, while another upgrade was
+	// signaled less than minUpgradeInterval ago, if positive
+	minUpgradeInterval time.Duration
+	intervalHeld       bool
+
+	// the upgrade is no longer signaled once it was signaled maxSignals times, if positive
+	maxSignals int
+	stateFile  string
+	state      watcherState
+
+	notifyStopped bool
+
+	// decisionLog is the file the decisions about upgrade plans are appended to, if set
+	decisionLog  string
+	lastDecision decisionRecord
+
+	// unreadableAlerted is when the last info_unreadable alert was sent, zero while the file is readable
+	unreadableAlerted time.Time
+	alertInterval     time.Duration
+
+	// an upgrade info file whose content failed to parse is not read again until it changes. If
+	// quarantineDir is set, it is moved there once it stayed invalid for quarantineAfter polls.
+	quarantineDir   string
+	quarantineAfter int
+	invalidDigest   []byte
+	invalidErr      error
+	invalidCount    int
+	// invalidCandidates are the files matching infoGlob which failed to parse, by path
+	invalidCandidates map[string]*invalidCandidate
+
+	logger    log.Logger
+	metrics   *metrics
+	callbacks *callbackDispatcher
 }
 
 func newUpgradeFileWatcher(cfg *Config, logger log.Logger) (*fileWatcher, error) {
@@ -58,48 +184,266 @@ func newUpgradeFileWatcher(cfg *Config, logger log.Logger) (*fileWatcher, error)
 		return nil, fmt.Errorf("invalid path: %s must be an existing directory: %w", dirname, err)
 	}
 
-	bin, err := cfg.CurrentBin()
+	if _, err := resolveCurrentBin(cfg, logger, time.Sleep); err != nil {
+		return nil, err
+	}
+
+	schema, err := loadUpgradeInfoSchema(cfg.UpgradeInfoSchemaPath)
 	if err != nil {
-		return nil, fmt.Errorf("error creating symlink to genesis: %w", err)
+		return nil, err
+	}
+
+	var m *metrics
+	if cfg.MetricsAddr != "" {
+		m = newMetrics()
+	}
+
+	// the watcher and its callbacks share the throttled logger, a failing callback endpoint
+	// being as repetitive as a failing height check
+	throttled := newThrottledLogger(logger, cfg.LogThrottleInterval)
+
+	fw := &fileWatcher{
+		resolveBin:         cfg.currentLinkBin,
+		filename:           filenameAbs,
+		infoGlob:           cfg.UpgradeInfoGlobPattern(),
+		currentUpgradeFile: cfg.CurrentUpgradeFilePath(),
+		interval:           cfg.PollInterval,
+		currentInfo:        upgradetypes.Plan{},
+		lastModTime:        time.Time{},
+		ticker:             time.NewTicker(cfg.PollInterval),
+		needsUpdate:        false,
+		initialized:        false,
+		restartDelay:       cfg.RestartHeuristicDelay,
+		sleep:              time.Sleep,
+		scanOnStart:        cfg.ScanOnStart,
+		parseOpts:          append(cfg.UpgradeInfoParseOptions(), ParseOptionSchema(schema)),
+		minFileAge:         cfg.MinFileAge,
+		byModTime:          cfg.ChangeDetection != ChangeDetectionHash,
+		byHash:             cfg.ChangeDetection == ChangeDetectionHash || cfg.ChangeDetection == ChangeDetectionBoth,
+		verifyHeight:       cfg.VerifyHeightReached,
+		heightTolerance:    cfg.HeightTolerance,
+		heightGracePeriod:  cfg.HeightGracePeriod,
+		heightFailClosed:   cfg.HeightFailurePolicy == HeightFailureClosed,
+		checkAppVersion:    cfg.VerifyAppVersion,
+		cosmovisorVersion:  buildVersion(),
+		statusTimeout:      cfg.StatusTimeout,
+		statusArgs:         cfg.StatusArgs,
+		now:                time.Now,
+		pendingInterval:    cfg.PendingInterval,
+		notifyPending:      cfg.CallbackUpgradePending,
+		heightMilestones:   cfg.HeightMilestones,
+		imminentBlocks:     cfg.CallbackImminentBlocks,
+		heartbeatInterval:  cfg.CallbackHeartbeat,
+		platformPreference: cfg.PlatformPreference,
+		nameVersions:       cfg.NameVersionMap,
+		postHook:           cfg.PostHeightReachedHookPath(),
+		postHookDir:        cfg.Home,
+		postHookTimeout:    cfg.PostHeightReachedTimeout,
+		postHookAbort:      cfg.PostHeightReachedPolicy != HookFailureContinue,
+		confirmation:       newUpgradeConfirmation(cfg),
+		confirmationAbort:  cfg.ConfirmationAbort,
+		gate:               newUpgradeGate(cfg),
+		minFreeDisk:        cfg.MinFreeDiskBytes,
+		diskDirs:           []string{filepath.Join(cfg.Home, "data"), cfg.Root()},
+		diskFree:           diskFree,
+		minUpgradeInterval: cfg.MinUpgradeInterval,
+		maxSignals:         cfg.MaxUpgradeSignals,
+		stateFile:          cfg.StateFilePath(),
+		notifyStopped:      cfg.CallbackWatcherStopped,
+		decisionLog:        cfg.DecisionLogPath,
+		alertInterval:      cfg.LogThrottleInterval,
+		quarantineDir:      cfg.QuarantineDir,
+		quarantineAfter:    cfg.QuarantineAfter,
+		logger:             throttled,
+		metrics:            m,
+		callbacks:          newCallbackDispatcher(cfg, throttled, m),
+	}
+	fw.getHeight = fw.checkHeight
+	fw.getAppVersion = fw.queryAppVersion
+	fw.startedAt = fw.now()
+
+	if fw.state, err = loadWatcherState(fw.stateFile); err != nil {
+		logger.Error("failed to load the watcher state, starting afresh", "error", err)
+	}
+
+	return fw, nil
+}
+
+// defaultBinResolveDelay is used when Config.BinResolveDelay is not set.
+const defaultBinResolveDelay = time.Second
+
+// resolveCurrentBin resolves the current binary. It is tried again up to cfg.BinResolveRetries
+// times, cfg.BinResolveDelay apart, while it cannot be resolved or does not exist yet, e.g. while
+// the volume holding it is still being mounted. Once the retries are exhausted it fails with the
+// same error as without retries.
+func resolveCurrentBin(cfg *Config, logger log.Logger, sleep func(time.Duration)) (string, error) {
+	delay := cfg.BinResolveDelay
+	if delay <= 0 {
+		delay = defaultBinResolveDelay
 	}
 
-	return &fileWatcher{
-		currentBin:    bin,
-		filename:      filenameAbs,
-		interval:      cfg.PollInterval,
-		currentInfo:   upgradetypes.Plan{},
-		lastModTime:   time.Time{},
-		cancel:        make(chan bool),
-		ticker:        time.NewTicker(cfg.PollInterval),
-		needsUpdate:   false,
-		initialized:   false,
-		disableRecase: cfg.DisableRecase,
-	}, nil
+	for attempt := 1; ; attempt++ {
+		bin, err := cfg.CurrentBin()
+		if err == nil && cfg.BinResolveRetries > 0 {
+			if _, statErr := os.Stat(bin); statErr != nil {
+				err = fmt.Errorf("current binary not found: %w", statErr)
+			}
+		}
+		if err == nil {
+			return bin, nil
+		}
+		if attempt > cfg.BinResolveRetries {
+			return "", fmt.Errorf("error creating symlink to genesis: %w", err)
+		}
+
+		logger.Info("current binary not available yet, retrying", "attempt", attempt, "retries", cfg.BinResolveRetries, "delay", delay, "error", err)
+		sleep(delay)
+	}
 }
 
+// Stop stops monitoring for upgrades, waiting for the monitoring loop to exit. If enabled, a
+// watcher_stopped callback carrying the last known height and upgrade is sent before returning,
+// giving up after watcherStoppedTimeout.
 func (fw *fileWatcher) Stop() {
-	close(fw.cancel)
+	fw.stop(nil)
+}
+
+// stop is Stop reporting the error the app exited with, if any, in the watcher_stopped callback.
+func (fw *fileWatcher) stop(exitErr error) {
+	fw.stopMonitor()
+	fw.sendStopped(exitErr)
+}
+
+// stopMonitor stops the running MonitorUpdate loop, waiting for it to exit.
+func (fw *fileWatcher) stopMonitor() {
+	fw.monitorMu.Lock()
+	cancel, exited := fw.monitorCancel, fw.monitorExited
+	fw.monitorCancel = nil
+	fw.monitorMu.Unlock()
+
+	// nil if not running, or already stopped
+	if cancel != nil {
+		close(cancel)
+	}
+	if exited != nil {
+		<-exited
+	}
+}
+
+// sendStopped sends the watcher_stopped callback, if enabled, reporting the error the app exited
+// with, if any.
+func (fw *fileWatcher) sendStopped(exitErr error) {
+	if !fw.notifyStopped {
+		return
+	}
+
+	callback := callbackInfo{
+		Name:          fw.lastDetected.Name,
+		Info:          fw.lastDetected.Info,
+		Height:        fw.lastDetected.Height,
+		CurrentHeight: fw.lastHeight,
+	}
+	if exitErr != nil {
+		callback.Error = exitErr.Error()
+	}
+	_ = fw.callbacks.sendTimeout(CallbackEventWatcherStopped, callback, watcherStoppedTimeout)
+}
+
+// Pause stops polling for upgrades until Resume is called. The watcher keeps its state, so a
+// plan file written while paused is picked up once resumed.
+// It is safe to call concurrently with a running MonitorUpdate, and waits for an in-flight check
+// to complete before returning. Polling stays paused when the MonitorUpdate loop is stopped and
+// started again.
+func (fw *fileWatcher) Pause() {
+	fw.pauseMu.Lock()
+	fw.paused = true
+	fw.ticker.Stop()
+	fw.pauseMu.Unlock()
+
+	fw.checking.Lock()
+	defer fw.checking.Unlock()
+}
+
+// Resume restarts polling for upgrades after Pause.
+func (fw *fileWatcher) Resume() {
+	fw.pauseMu.Lock()
+	defer fw.pauseMu.Unlock()
+
+	if fw.paused {
+		fw.paused = false
+		fw.ticker.Reset(fw.interval)
+	}
+}
+
+// isPaused reports whether polling is paused.
+func (fw *fileWatcher) isPaused() bool {
+	fw.pauseMu.Lock()
+	defer fw.pauseMu.Unlock()
+
+	return fw.paused
 }
 
 // MonitorUpdate pools the filesystem to check for new upgrade currentInfo.
 // currentName is the name of currently running upgrade.  The check is rejected if it finds
 // an upgrade with the same name.
+// The returned channel is closed once an upgrade is needed. Only one monitoring loop runs at a
+// time: calling MonitorUpdate while it is running returns the channel of the running loop.
 func (fw *fileWatcher) MonitorUpdate(currentUpgrade upgradetypes.Plan) <-chan struct{} {
-	fw.ticker.Reset(fw.interval)
-	done := make(chan struct{})
-	fw.cancel = make(chan bool)
+	fw.monitorMu.Lock()
+	defer fw.monitorMu.Unlock()
+
+	if fw.monitorDone != nil {
+		fw.logger.Error("upgrade monitoring is already running, ignoring the new request")
+		return fw.monitorDone
+	}
+
+	fw.pauseMu.Lock()
+	if !fw.paused {
+		fw.ticker.Reset(fw.interval)
+	}
+	fw.pauseMu.Unlock()
+	done, cancel, exited := make(chan struct{}), make(chan struct{}), make(chan struct{})
+	fw.monitorDone = done
+	fw.monitorCancel = cancel
+	fw.monitorExited = exited
 	fw.needsUpdate = false
+	fw.appExited = false
+
+	// check reports whether an upgrade is needed, unless paused
+	check := func() bool {
+		fw.checking.Lock()
+		defer fw.checking.Unlock()
+
+		fw.heartbeat()
+		return !fw.isPaused() && fw.CheckUpdate(currentUpgrade)
+	}
 
 	go func() {
+		defer func() {
+			fw.monitorMu.Lock()
+			fw.monitorDone = nil
+			fw.monitorCancel = nil
+			fw.monitorExited = nil
+			fw.monitorMu.Unlock()
+			close(exited)
+		}()
+
+		// don't wait for the first tick to pick up an upgrade-info.json already present
+		if fw.scanOnStart && check() {
+			close(done)
+			return
+		}
+
 		for {
 			select {
 			case <-fw.ticker.C:
-				if fw.CheckUpdate(currentUpgrade) {
-					done <- struct{}{}
+				// a tick may already be pending when paused
+				if check() {
+					close(done)
 					return
 				}
 
-			case <-fw.cancel:
+			case <-cancel:
 				return
 			}
 		}
@@ -116,33 +460,117 @@ func (fw *fileWatcher) CheckUpdate(currentUpgrade upgradetypes.Plan) bool {
 		return true
 	}
 
+	if fw.confirmation.awaiting() {
+		return fw.checkConfirmation()
+	}
+
+	if fw.upgradeHeld() {
+		return fw.checkHeld(currentUpgrade)
+	}
+
+	return fw.checkInfoFile(currentUpgrade)
+}
+
+// checkHeld checks again whether the held upgrade of currentInfo can be signaled. A new trigger
+// in the upgrade info file is checked first as usual, taking over the hold if it replaces
+// currentInfo.
+func (fw *fileWatcher) checkHeld(currentUpgrade upgradetypes.Plan) bool {
+	held := fw.currentInfo
+	if fw.checkInfoFile(currentUpgrade) {
+		return true
+	}
+
+	if !fw.upgradeHeld() || fw.currentInfo.Height != held.Height || planAmended(fw.currentInfo, held) {
+		return false
+	}
+
+	if !fw.signalUpgrade(fw.currentInfo, fw.heldCallback) {
+		return false
+	}
+
+	return fw.logUpgradeDecision(fw.currentInfo, true, reasonHoldReleased, "")
+}
+
+// checkInfoFile reads the upgrade info file, if it changed, and checks whether it requests a new
+// upgrade.
+func (fw *fileWatcher) checkInfoFile(currentUpgrade upgradetypes.Plan) bool {
+	if fw.infoGlob != "" && !fw.selectUpgradeInfoFile(currentUpgrade) {
+		return false
+	}
+
 	stat, err := os.Stat(fw.filename)
 	if err != nil {
 		// file doesn't exists
+		if !errors.Is(err, fs.ErrNotExist) {
+			fw.logger.Error("failed to stat upgrade info file", "file", fw.filename, "error", err)
+		}
+		return false
+	}
+
+	var digest []byte
+	if fw.byHash {
+		if digest, err = fileDigest(fw.filename); err != nil {
+			if errors.Is(err, ErrUpgradeInfoUnreadable) {
+				fw.alertUnreadable(err)
+				return false
+			}
+			fw.logger.Error("failed to hash upgrade info file", "file", fw.filename, "error", err)
+			return false
+		}
+	}
+	if !fw.changed(stat, digest) {
+		fw.quarantineInvalid()
 		return false
 	}
 
-	if !stat.ModTime().After(fw.lastModTime) {
+	// wait until the file has settled, external tooling may still be rewriting it
+	if fw.minFileAge > 0 && fw.now().Sub(stat.ModTime()) < fw.minFileAge {
 		return false
 	}
 
-	info, err := parseUpgradeInfoFile(fw.filename, fw.disableRecase)
+	info, err := ParseUpgradeInfoFile(fw.filename, fw.parseOpts...)
+	if errors.Is(err, ErrUpgradeInfoUnreadable) {
+		// keep watching, the file is picked up again once its permissions are fixed
+		fw.alertUnreadable(err)
+		return false
+	}
+	fw.clearUnreadable()
 	if err != nil {
-		panic(fmt.Errorf("failed to parse upgrade info file: %w", err))
+		fw.logDecision(decisionRecord{File: fw.filename, FileModTime: stat.ModTime(), CurrentHeight: fw.lastHeight, Reason: reasonInvalidPlan, Error: err.Error()})
+		fw.handleInvalid(err, stat.ModTime(), digest)
+		return false
+	}
+	fw.resetInvalid()
+
+	if !fw.checkCosmovisorVersion(info) {
+		// not acted upon until the plan changes, or a newer cosmovisor reads it
+		fw.logDecision(decisionRecord{File: fw.filename, FileModTime: stat.ModTime(), Plan: info, CurrentHeight: fw.lastHeight, Reason: reasonCosmovisorOld})
+		fw.lastModTime = stat.ModTime()
+		fw.lastDigest = digest
+		return false
 	}
 
 	// extract version number and github url (if possible) for upnode deploy upgrade request
 	version := ""
 	repo := ""
-	upgradeInfo, err := plan.ParseInfo(info.Info)
+	binaries, err := ParseUpgradeBinaries(info.Info)
 	if err == nil {
-		for _, url := range upgradeInfo.Binaries {
-			repo, version = getVersionAndRepoFromUrl(url)
+		for _, platform := range preferredPlatforms(binaries, fw.platformPreference) {
+			repo, version = getVersionAndRepoFromUrl(binaries[platform].URL)
 			if version != "" {
 				break
 			}
 		}
 	}
+	// offline setups can't rely on the binary URLs, fill in what they did not tell
+	if ref, ok := lookupVersionRef(fw.nameVersions, info.Name); ok {
+		if version == "" {
+			version = ref.Version
+		}
+		if repo == "" {
+			repo = ref.Repo
+		}
+	}
 
 	// callback even if no version number found, so the owner can at least be informed that an upgrade is expected
 	callback := callbackInfo{
@@ -152,57 +580,364 @@ func (fw *fileWatcher) CheckUpdate(currentUpgrade upgradetypes.Plan) bool {
 		Info:    info.Info,
 		Height:  info.Height,
 	}
-	callbackJson, err := json.Marshal(callback)
+	fw.lastDetected = info
+	_ = fw.callbacks.send(CallbackEventDetected, callback)
 
-	if err == nil {
-		upgradeDetectedCallback(&callbackJson)
+	// file exist but too early in height
+	currentHeight, err := fw.getHeight()
+	if err != nil {
+		fw.logger.Error("failed to check current height", "error", err)
+	}
+	if currentHeight > 0 {
+		fw.lastHeight = currentHeight
 	}
 
-	// file exist but too early in height
-	currentHeight, _ := fw.checkHeight()
+	// decide records the outcome of the check in the decision log
+	decide := func(upgrade bool, reason string) bool {
+		fw.logDecision(decisionRecord{
+			File:          fw.filename,
+			FileModTime:   stat.ModTime(),
+			Plan:          info,
+			CurrentHeight: currentHeight,
+			Upgrade:       upgrade,
+			Reason:        reason,
+		})
+		return upgrade
+	}
+
+	if currentHeight == 0 {
+		currentHeight = fw.fallbackHeight(info)
+	}
+	if currentHeight == 0 && fw.holdUnknownHeight(info) {
+		return decide(false, reasonHeightUnknown)
+	}
 	if currentHeight != 0 && currentHeight < info.Height {
-		return false
+		fw.fireMilestones(info, callback, currentHeight)
+		fw.alertImminent(info, callback, currentHeight)
+		fw.reportPending(info, callback, currentHeight)
+		fw.alertDiskSpace(info, callback)
+		return decide(false, reasonHeightNotReached)
 	}
 
 	if !fw.initialized {
-		// daemon has restarted
-		fw.initialized = true
-		fw.currentInfo = info
-		fw.lastModTime = stat.ModTime()
-
 		// Heuristic: Deamon has restarted, so we don't know if we successfully
 		// downloaded the upgrade or not. So we try to compare the running upgrade
 		// name (read from the cosmovisor file) with the upgrade info.
+		if currentUpgrade.Name == "" {
+			currentUpgrade = fw.readCurrentUpgrade()
+		}
+		if !strings.EqualFold(currentUpgrade.Name, info.Name) && fw.restartDelay > 0 {
+			var height int64
+			if currentUpgrade, height = fw.verifyRestart(info, currentUpgrade); height > 0 {
+				currentHeight = height
+			}
+			if currentHeight != 0 && currentHeight < info.Height {
+				// a transient startup state, the plan is considered again on the next poll
+				return decide(false, reasonHeightNotReached)
+			}
+		}
+
+		// daemon has restarted
+		fw.initialized = true
+		fw.track(info, stat.ModTime(), digest)
+
 		if !strings.EqualFold(currentUpgrade.Name, fw.currentInfo.Name) {
-			fw.needsUpdate = true
-			upgradeHeightReachedCallback(&callbackJson)
-			return true
+			return decide(fw.upgradeReached(info, callback), reasonHeightReached)
 		}
 	}
 
 	if info.Height > fw.currentInfo.Height {
-		fw.currentInfo = info
-		fw.lastModTime = stat.ModTime()
-		fw.needsUpdate = true
-		upgradeHeightReachedCallback(&callbackJson)
+		fw.track(info, stat.ModTime(), digest)
+		return decide(fw.upgradeReached(info, callback), reasonHeightReached)
+	}
+
+	// the plan was corrected without bumping its height
+	if info.Height == fw.currentInfo.Height && planAmended(fw.currentInfo, info) {
+		fw.logger.Info("upgrade plan amended at the same height", "height", info.Height, "previous", fw.currentInfo.Name, "name", info.Name)
+		fw.track(info, stat.ModTime(), digest)
+		_ = fw.callbacks.send(CallbackEventPlanAmended, callback)
+		return decide(fw.upgradeReached(info, callback), reasonPlanAmended)
+	}
+
+	// the plan was reverted to an earlier upgrade by hand, track it again without upgrading
+	if info.Height < fw.currentInfo.Height {
+		fw.logger.Info("upgrade plan rolled back", "height", info.Height, "name", info.Name, "previous_height", fw.currentInfo.Height, "previous", fw.currentInfo.Name)
+		callback.PreviousName = fw.currentInfo.Name
+		callback.PreviousHeight = fw.currentInfo.Height
+		fw.track(info, stat.ModTime(), digest)
+		_ = fw.callbacks.send(CallbackEventRollbackDetected, callback)
+		return decide(false, reasonPlanRolledBack)
+	}
+
+	return decide(false, reasonAlreadyHandled)
+}
+
+// track makes info the tracked upgrade plan, read from a file with the given modification time
+// and digest. A hold of the previous plan is released.
+func (fw *fileWatcher) track(info upgradetypes.Plan, modTime time.Time, digest []byte) {
+	fw.currentInfo = info
+	fw.lastModTime = modTime
+	fw.lastDigest = digest
+	fw.gateHeld = false
+	fw.diskHeld = false
+	fw.intervalHeld = false
+}
+
+// upgradeHeld reports whether the upgrade of currentInfo is held by the upgrade gate, a lack of
+// disk space or the minimum upgrade interval.
+func (fw *fileWatcher) upgradeHeld() bool {
+	return fw.gateHeld || fw.diskHeld || fw.intervalHeld
+}
+
+// checkAfterExit checks for an upgrade once the app exited, as the app x/upgrade panic can kill
+// it before the upgrade was picked up. While the upgrade awaits its confirmation or is held, it
+// keeps checking every poll interval, so that the upgrade is not lost with the app, and returns
+// once it is signaled, or no longer pending, e.g. because its confirmation was aborted.
+func (fw *fileWatcher) checkAfterExit(currentUpgrade upgradetypes.Plan) bool {
+	fw.appExited = true
+	for !fw.CheckUpdate(currentUpgrade) {
+		if !fw.confirmation.awaiting() && !fw.upgradeHeld() {
+			return false
+		}
+
+		fw.logger.Info("app exited while the upgrade is pending, waiting", "name", fw.currentInfo.Name, "height", fw.currentInfo.Height)
+		fw.sleep(fw.interval)
+	}
+
+	return true
+}
+
+// changed reports whether the file changed since it was last acted upon: when its modification
+// time is later and/or its content digest differs, depending on the change detection strategy.
+func (fw *fileWatcher) changed(stat os.FileInfo, digest []byte) bool {
+	if fw.byModTime && stat.ModTime().After(fw.lastModTime) {
 		return true
 	}
 
-	return false
+	return fw.byHash && !bytes.Equal(digest, fw.lastDigest)
+}
+
+// fileDigest returns the sha256 digest of the file content.
+func fileDigest(filename string) ([]byte, error) {
+	bz, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, unreadableError(err)
+	}
+
+	digest := sha256.Sum256(bz)
+	return digest[:], nil
+}
+
+// alertUnreadable logs that the upgrade info file cannot be read and sends an info_unreadable
+// callback, at most once per alertInterval.
+func (fw *fileWatcher) alertUnreadable(err error) {
+	fw.logger.Error("upgrade info file is not readable, check its permissions", "file", fw.filename, "error", err)
+
+	interval := fw.alertInterval
+	if interval <= 0 {
+		interval = defaultLogThrottleInterval
+	}
+	now := fw.now()
+	if !fw.unreadableAlerted.IsZero() && now.Sub(fw.unreadableAlerted) < interval {
+		return
+	}
+	fw.unreadableAlerted = now
+	_ = fw.callbacks.send(CallbackEventInfoUnreadable, callbackInfo{Error: err.Error()})
+}
+
+// clearUnreadable resets the info_unreadable alert once the upgrade info file can be read again.
+func (fw *fileWatcher) clearUnreadable() {
+	if fw.unreadableAlerted.IsZero() {
+		return
+	}
+	fw.unreadableAlerted = time.Time{}
+	fw.logger.Info("upgrade info file is readable again", "file", fw.filename)
+}
+
+// planAmended reports whether two plans at the same height differ in content.
+func planAmended(previous, current upgradetypes.Plan) bool {
+	return previous.Name != current.Name || previous.Info != current.Info
+}
+
+// upgradeReached flags the upgrade as needed once its height is reached, or starts waiting for
+// its confirmation if required.
+func (fw *fileWatcher) upgradeReached(info upgradetypes.Plan, callback callbackInfo) bool {
+	if !fw.verifyAppVersion(info, callback) {
+		return false
+	}
+
+	_ = fw.callbacks.send(CallbackEventReached, callback)
+	fw.verifyHaltHeight(info, callback)
+	if err := fw.runPostHeightReachedHook(info, callback); err != nil && fw.postHookAbort {
+		fw.logger.Error("not upgrading as COSMOVISOR_POST_HEIGHT_REACHED_HOOK failed, fix it and restart cosmovisor to retry", "name", info.Name)
+		return fw.logUpgradeDecision(info, false, reasonHookFailed, err.Error())
+	}
+
+	if fw.confirmation != nil {
+		fw.logger.Info("upgrade height reached, waiting for confirmation", "name", info.Name, "file", fw.confirmation.file)
+		fw.pendingCallback = callback
+		fw.confirmation.await(info.Name)
+		return fw.checkConfirmation()
+	}
+
+	return fw.signalUpgrade(info, callback)
+}
+
+// signalUpgrade flags the upgrade as needed, unless held by a lack of disk space, the upgrade
+// gate or the minimum upgrade interval. The number of times the same upgrade is signaled is
+// persisted: once it exceeds the maximum the upgrade is considered broken, an upgrade_failed
+// callback is emitted instead and the upgrade is no longer signaled.
+func (fw *fileWatcher) signalUpgrade(info upgradetypes.Plan, callback callbackInfo) bool {
+	if !fw.checkDiskSpace(info, callback) {
+		return fw.logUpgradeDecision(info, false, reasonDiskSpaceLow, "")
+	}
+	if !fw.checkGate(info, callback) {
+		return fw.logUpgradeDecision(info, false, reasonGateClosed, "")
+	}
+	if !fw.checkUpgradeInterval(info, callback) {
+		return fw.logUpgradeDecision(info, false, reasonIntervalNotElapsed, "")
+	}
+
+	last := fw.state.LastSignal
+	if last == nil || last.Name != info.Name || last.Height != info.Height {
+		last = &upgradeSignal{Name: info.Name, Height: info.Height}
+	}
+
+	if fw.maxSignals > 0 && last.Count >= fw.maxSignals {
+		fw.logger.Error("upgrade signaled too many times, giving up", "name", info.Name, "height", info.Height, "signals", last.Count)
+		callback.Error = fmt.Sprintf("upgrade signaled %d times without succeeding", last.Count)
+		_ = fw.callbacks.send(CallbackEventUpgradeFailed, callback)
+		return fw.logUpgradeDecision(info, false, reasonSignalLimit, callback.Error)
+	}
+
+	last.Count++
+	fw.state.LastSignal = last
+	fw.state.LastSignalTime = fw.now().UTC()
+	if err := saveWatcherState(fw.stateFile, fw.state); err != nil {
+		fw.logger.Error("failed to save the watcher state", "file", fw.stateFile, "error", err)
+	}
+
+	fw.needsUpdate = true
+	return true
+}
+
+// checkConfirmation reports whether the upgrade awaiting confirmation was confirmed.
+// Once the confirmation timeout elapses a confirmation_timeout callback is emitted and the upgrade is
+// either held until confirmed, or aborted.
+func (fw *fileWatcher) checkConfirmation() bool {
+	confirmed, timedOut := fw.confirmation.check()
+	if confirmed {
+		fw.logger.Info("upgrade confirmed", "name", fw.pendingCallback.Name)
+		if !fw.signalUpgrade(fw.currentInfo, fw.pendingCallback) {
+			return false
+		}
+		return fw.logUpgradeDecision(fw.currentInfo, true, reasonConfirmed, "")
+	}
+
+	if timedOut {
+		callback := fw.pendingCallback
+		if fw.confirmationAbort {
+			fw.logger.Error("upgrade not confirmed in time, aborting", "name", callback.Name)
+			callback.Error = "not confirmed in time, aborted"
+			// stop waiting: the upgrade is never signaled
+			fw.confirmation.await("")
+		} else {
+			fw.logger.Error("upgrade not confirmed in time, holding until confirmed", "name", callback.Name)
+			callback.Error = "not confirmed in time, holding"
+		}
+		_ = fw.callbacks.send(CallbackEventNotConfirmed, callback)
+		return fw.logUpgradeDecision(fw.currentInfo, false, reasonNotConfirmed, callback.Error)
+	}
+
+	return fw.logUpgradeDecision(fw.currentInfo, false, reasonAwaitingConfirmation, "")
 }
 
-func upgradeDetectedCallback(callbackJson *[]byte) {
-	// report upgrade requirement back to upnode deploy
-	callbackUrl := os.Getenv("CALLBACK_API") + "/internal/cosmos/" + os.Getenv("NODE_ID") + "/" + os.Getenv("DEPLOYMENT_ID") + "/cosmos_notify_upgrade"
-	fmt.Println("upgrade callback to " + callbackUrl)
-	http.Post(callbackUrl, "application/json", bytes.NewBuffer(*callbackJson))
+// verifyHaltHeight re-reads the current height to check the node halted at the upgrade height,
+// emitting a height_overrun callback if it went past it by more than the configured tolerance.
+// The upgrade proceeds either way. The check is skipped if disabled or if the height is unknown.
+func (fw *fileWatcher) verifyHaltHeight(info upgradetypes.Plan, callback callbackInfo) {
+	if !fw.verifyHeight {
+		return
+	}
+
+	height, err := fw.getHeight()
+	if err != nil || height == 0 {
+		fw.logger.Info("current height unknown, skipping halt height verification", "error", err)
+		return
+	}
+
+	if height-info.Height <= fw.heightTolerance {
+		return
+	}
+
+	fw.logger.Error("node went past the upgrade height, it may have missed the upgrade halt",
+		"upgrade height", info.Height, "current height", height, "tolerance", fw.heightTolerance)
+	callback.CurrentHeight = height
+	_ = fw.callbacks.send(CallbackEventHeightOverrun, callback)
 }
 
-func upgradeHeightReachedCallback(callbackJson *[]byte) {
-	// send an alert to notify the backend that the upgrade height has been reached
-	callbackUrl := os.Getenv("CALLBACK_API") + "/internal/cosmos/" + os.Getenv("NODE_ID") + "/" + os.Getenv("DEPLOYMENT_ID") + "/cosmos_upgrade_height_reached"
-	fmt.Println("upgrade height callback to " + callbackUrl)
-	http.Post(callbackUrl, "application/json", bytes.NewBuffer(*callbackJson))
+// readCurrentUpgrade returns the last upgrade applied by cosmovisor, or an empty plan if
+// none was recorded.
+func (fw *fileWatcher) readCurrentUpgrade() upgradetypes.Plan {
+	var u upgradetypes.Plan
+	if fw.currentUpgradeFile == "" {
+		return u
+	}
+
+	bz, err := os.ReadFile(fw.currentUpgradeFile)
+	if err != nil {
+		return u
+	}
+
+	if err := json.Unmarshal(bz, &u); err != nil {
+		fw.logger.Error("failed to parse current upgrade file", "file", fw.currentUpgradeFile, "error", err)
+		return upgradetypes.Plan{}
+	}
+
+	return u
+}
+
+// verifyRestart waits restartDelay and then reads the running upgrade and the node height again,
+// as the node may report a transient state while it starts. The upgrade running is returned,
+// currentUpgrade unless the current upgrade file tells otherwise, and the height, 0 if unknown.
+func (fw *fileWatcher) verifyRestart(info, currentUpgrade upgradetypes.Plan) (upgradetypes.Plan, int64) {
+	fw.logger.Info("running upgrade differs from the upgrade plan, verifying again", "running", currentUpgrade.Name, "name", info.Name, "delay", fw.restartDelay)
+	fw.sleep(fw.restartDelay)
+
+	if running := fw.readCurrentUpgrade(); running.Name != "" {
+		currentUpgrade = running
+	}
+
+	height, err := fw.getHeight()
+	if err != nil {
+		fw.logger.Error("failed to check current height", "error", err)
+	}
+	if height > 0 {
+		fw.lastHeight = height
+	}
+
+	return currentUpgrade, height
+}
+
+// preferredPlatforms returns the platforms listed in binaries, those of the preference order first
+// and the remaining ones after in alphabetical order.
+func preferredPlatforms(binaries map[string]BinaryRef, preference []string) []string {
+	var platforms, rest []string
+	preferred := make(map[string]bool)
+	for _, platform := range PlatformOrder(preference) {
+		if _, ok := binaries[platform]; ok && !preferred[platform] {
+			platforms = append(platforms, platform)
+			preferred[platform] = true
+		}
+	}
+	for platform := range binaries {
+		if !preferred[platform] {
+			rest = append(rest, platform)
+		}
+	}
+	sort.Strings(rest)
+
+	return append(platforms, rest...)
 }
 
 func getVersionAndRepoFromUrl(url string) (string, string) {
@@ -233,6 +968,16 @@ func getVersionAndRepoFromUrl(url string) (string, string) {
 	return repo, ver
 }
 
+// statusRetryDelay is how long checkHeight waits before retrying a status command whose
+// binary went missing, e.g. because the current symlink was being swapped.
+const statusRetryDelay = 100 * time.Millisecond
+
+// defaultStatusTimeout is used when no status command timeout is configured.
+const defaultStatusTimeout = 5 * time.Second
+
+// watcherStoppedTimeout bounds the time Stop waits for the watcher_stopped callback.
+var watcherStoppedTimeout = 2 * time.Second
+
 // checkHeight checks if the current block height
 func (fw *fileWatcher) checkHeight() (int64, error) {
 	// TODO(@julienrbrt) use `if !testing.Testing()` from Go 1.22
@@ -242,7 +987,59 @@ func (fw *fileWatcher) checkHeight() (int64, error) {
 		return 0, nil
 	}
 
-	result, err := exec.Command(fw.currentBin, "status").Output() //nolint:gosec // we want to execute the status command
+	return fw.queryHeight()
+}
+
+// holdUnknownHeight reports whether the upgrade to info is held while the block height is
+// unknown. Until a height was read, this is tolerated during the startup grace period, assuming
+// the upgrade height is not reached yet. Past it, or once a height was read, the upgrade is held
+// with the closed height failure policy only, the open one acting on the plan as if its height
+// was reached.
+func (fw *fileWatcher) holdUnknownHeight(info upgradetypes.Plan) bool {
+	if fw.heightGracePeriod > 0 && fw.lastHeight == 0 && fw.now().Sub(fw.startedAt) < fw.heightGracePeriod {
+		fw.logger.Info("block height unknown during the startup grace period, waiting", "name", info.Name, "height", info.Height)
+		return true
+	}
+
+	if fw.heightFailClosed {
+		fw.logger.Error("block height unknown, holding the upgrade", "name", info.Name, "height", info.Height)
+		return true
+	}
+
+	return false
+}
+
+// fallbackHeight returns the height to act upon while the block height is unknown, 0 if none.
+// Once the app exited, the last known height is used as it no longer advances, and the upgrade
+// height is considered reached if the last known height is within the height tolerance of it, as
+// the app halts at the upgrade height before committing its block. While the app is running, the
+// last known height is only used if it is within the height tolerance of the upgrade height.
+func (fw *fileWatcher) fallbackHeight(info upgradetypes.Plan) int64 {
+	height := int64(0)
+	switch {
+	case fw.lastHeight == 0:
+		return 0
+	case fw.appExited && fw.lastHeight < info.Height && info.Height-fw.lastHeight <= fw.heightTolerance+1:
+		height = info.Height
+	case fw.appExited || fw.lastHeight >= info.Height-fw.heightTolerance:
+		height = fw.lastHeight
+	default:
+		return 0
+	}
+
+	fw.logger.Info("block height unknown, using the last known height", "name", info.Name, "height", info.Height, "last height", fw.lastHeight, "app exited", fw.appExited)
+	return height
+}
+
+// queryHeight runs the status command of the current binary and returns the latest block height.
+// The current binary is resolved on every call, so a repointed current symlink is picked up.
+func (fw *fileWatcher) queryHeight() (int64, error) {
+	result, err := fw.execStatus()
+	if errors.Is(err, fs.ErrNotExist) {
+		// the current symlink may be in the middle of an upgrade swap, give it a moment to settle
+		fw.sleep(statusRetryDelay)
+		result, err = fw.execStatus()
+	}
 	if err != nil {
 		return 0, err
 	}
@@ -265,30 +1062,40 @@ func (fw *fileWatcher) checkHeight() (int64, error) {
 	return strconv.ParseInt(resp.SyncInfo.LatestBlockHeight, 10, 64)
 }
 
-func parseUpgradeInfoFile(filename string, disableRecase bool) (upgradetypes.Plan, error) {
-	f, err := os.ReadFile(filename)
+// execStatus resolves the current binary and executes its status command, with the configured
+// extra arguments.
+func (fw *fileWatcher) execStatus() ([]byte, error) {
+	return fw.execApp(false, append([]string{"status"}, fw.statusArgs...)...)
+}
+
+// execApp resolves the current binary and executes it with the given arguments, returning its
+// output, along with its stderr if combined is set.
+func (fw *fileWatcher) execApp(combined bool, args ...string) ([]byte, error) {
+	bin, err := fw.resolveBin()
 	if err != nil {
-		return upgradetypes.Plan{}, err
+		return nil, err
 	}
 
-	if len(f) == 0 {
-		return upgradetypes.Plan{}, errors.New("empty upgrade-info.json")
+	timeout := fw.statusTimeout
+	if timeout <= 0 {
+		timeout = defaultStatusTimeout
 	}
 
-	var upgradePlan upgradetypes.Plan
-	if err := json.Unmarshal(f, &upgradePlan); err != nil {
-		return upgradetypes.Plan{}, err
-	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
 
-	// required values must be set
-	if err := upgradePlan.ValidateBasic(); err != nil {
-		return upgradetypes.Plan{}, fmt.Errorf("invalid upgrade-info.json content: %w, got: %v", err, upgradePlan)
+	cmd := exec.CommandContext(ctx, bin, args...) //nolint:gosec // we want to execute the app commands
+	// don't wait on output pipes held open by processes the command left behind
+	cmd.WaitDelay = time.Second
+	var result []byte
+	if combined {
+		result, err = cmd.CombinedOutput()
+	} else {
+		result, err = cmd.Output()
 	}
-
-	// normalize name to prevent operator error in upgrade name case sensitivity errors.
-	if !disableRecase {
-		upgradePlan.Name = strings.ToLower(upgradePlan.Name)
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return nil, fmt.Errorf("%s command timed out after %s", args[0], timeout)
 	}
 
-	return upgradePlan, err
+	return result, err
 }
@@ -1,11 +1,26 @@
 package cosmovisor
 
 import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
 	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
 
+	"cosmossdk.io/log"
 	upgradetypes "cosmossdk.io/x/upgrade/types"
 )
 
@@ -82,7 +97,7 @@ func TestParseUpgradeInfoFile(t *testing.T) {
 		tc := cases[i]
 		t.Run(tc.filename, func(t *testing.T) {
 			require := require.New(t)
-			ui, err := parseUpgradeInfoFile(filepath.Join(".", "testdata", "upgrade-files", tc.filename), tc.disableRecase)
+			ui, err := ParseUpgradeInfoFile(filepath.Join(".", "testdata", "upgrade-files", tc.filename), ParseOptionDisableRecase(tc.disableRecase))
 			if tc.expectErr {
 				require.Error(err)
 			} else {
@@ -92,3 +107,1571 @@ func TestParseUpgradeInfoFile(t *testing.T) {
 		})
 	}
 }
+
+// writeStatusBin writes a fake app binary whose status command reports the given height.
+func writeStatusBin(t *testing.T, path string, height int64) {
+	t.Helper()
+
+	script := fmt.Sprintf("#!/bin/sh\necho '{\"SyncInfo\":{\"latest_block_height\":\"%d\"}}'\n", height)
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+	require.NoError(t, os.WriteFile(path, []byte(script), 0o755)) //nolint:gosec // the fake binary must be executable
+}
+
+func TestQueryHeightSymlinkSwap(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("status scripts require a unix shell")
+	}
+
+	require := require.New(t)
+	cfg := &Config{Home: t.TempDir(), Name: "dummyd"}
+	writeStatusBin(t, cfg.UpgradeBin("chain2"), 42)
+
+	// current points to an upgrade which is not in place yet
+	link := filepath.Join(cfg.Root(), currentLink)
+	require.NoError(os.Symlink(cfg.UpgradeDir("chain3"), link))
+
+	resolved := 0
+	var slept []time.Duration
+	fw := &fileWatcher{
+		resolveBin: func() (string, error) {
+			bin, err := cfg.currentLinkBin()
+			resolved++
+			if resolved == 1 {
+				// the upgrade swap completes between resolution and exec
+				require.NoError(os.Remove(link))
+				require.NoError(os.Symlink(cfg.UpgradeDir("chain2"), link))
+			}
+			return bin, err
+		},
+		sleep: func(d time.Duration) { slept = append(slept, d) },
+	}
+
+	height, err := fw.queryHeight()
+	require.NoError(err)
+	require.Equal(int64(42), height)
+	require.Equal(2, resolved)
+	require.Equal([]time.Duration{statusRetryDelay}, slept)
+}
+
+func TestQueryHeightMissingBinary(t *testing.T) {
+	cfg := &Config{Home: t.TempDir(), Name: "dummyd"}
+	require.NoError(t, os.MkdirAll(cfg.Root(), 0o755))
+	require.NoError(t, os.Symlink(cfg.UpgradeDir("chain3"), filepath.Join(cfg.Root(), currentLink)))
+
+	fw := &fileWatcher{resolveBin: cfg.currentLinkBin, sleep: func(time.Duration) {}}
+	_, err := fw.queryHeight()
+	require.ErrorIs(t, err, fs.ErrNotExist)
+}
+
+func TestQueryHeightMissingCurrentLink(t *testing.T) {
+	cfg := &Config{Home: t.TempDir(), Name: "dummyd"}
+	writeStatusBin(t, cfg.GenesisBin(), 42)
+
+	// the current symlink is being swapped, it is not replaced by a link to genesis
+	fw := &fileWatcher{resolveBin: cfg.currentLinkBin, sleep: func(time.Duration) {}}
+	_, err := fw.queryHeight()
+	require.ErrorIs(t, err, fs.ErrNotExist)
+	require.NoFileExists(t, filepath.Join(cfg.Root(), currentLink))
+}
+
+func TestQueryHeightTimeout(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the stub binary is a shell script")
+	}
+
+	dir := t.TempDir()
+	bin := filepath.Join(dir, "dummyd")
+	pidFile := filepath.Join(dir, "pid")
+	script := fmt.Sprintf("#!/bin/sh\necho $$ > %s\nexec sleep 10\n", pidFile)
+	require.NoError(t, os.WriteFile(bin, []byte(script), 0o755)) //nolint:gosec // the fake binary must be executable
+
+	fw := &fileWatcher{
+		resolveBin:    func() (string, error) { return bin, nil },
+		statusTimeout: 100 * time.Millisecond,
+	}
+
+	start := time.Now()
+	_, err := fw.queryHeight()
+	require.EqualError(t, err, "status command timed out after 100ms")
+	require.Less(t, time.Since(start), 5*time.Second)
+
+	// the status command was killed
+	bz, err := os.ReadFile(pidFile)
+	require.NoError(t, err)
+	pid, err := strconv.Atoi(strings.TrimSpace(string(bz)))
+	require.NoError(t, err)
+	proc, err := os.FindProcess(pid)
+	require.NoError(t, err)
+	require.Error(t, proc.Signal(syscall.Signal(0)))
+}
+
+func TestQueryHeightStatusArgs(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the stub binary is a shell script")
+	}
+
+	dir := t.TempDir()
+	bin := filepath.Join(dir, "dummyd")
+	argsFile := filepath.Join(dir, "args")
+	script := fmt.Sprintf("#!/bin/sh\nprintf '%%s\\n' \"$@\" > %s\necho '{\"SyncInfo\":{\"latest_block_height\":\"42\"}}'\n", argsFile)
+	require.NoError(t, os.WriteFile(bin, []byte(script), 0o755)) //nolint:gosec // the fake binary must be executable
+
+	fw := &fileWatcher{
+		resolveBin: func() (string, error) { return bin, nil },
+		statusArgs: []string{"--node", "tcp://localhost:26657", "--home", "/var/lib/node"},
+	}
+
+	height, err := fw.queryHeight()
+	require.NoError(t, err)
+	require.Equal(t, int64(42), height)
+
+	bz, err := os.ReadFile(argsFile)
+	require.NoError(t, err)
+	require.Equal(t, "status\n--node\ntcp://localhost:26657\n--home\n/var/lib/node\n", string(bz))
+}
+
+// newTestWatcher returns a file watcher for the upgrade-info.json of the given config.
+func newTestWatcher(t *testing.T, cfg *Config) *fileWatcher {
+	t.Helper()
+
+	require.NoError(t, os.MkdirAll(filepath.Dir(cfg.UpgradeInfoFilePath()), 0o755))
+	require.NoError(t, os.MkdirAll(cfg.Root(), 0o755))
+	writeStatusBin(t, cfg.GenesisBin(), 0)
+	if cfg.PollInterval == 0 {
+		cfg.PollInterval = time.Millisecond
+	}
+
+	fw, err := newUpgradeFileWatcher(cfg, log.NewNopLogger())
+	require.NoError(t, err)
+
+	return fw
+}
+
+// writeCurrentUpgrade records the given plan as the applied upgrade, as SetCurrentUpgrade does:
+// the current link points to its directory, holding its binary and upgrade-info.json.
+func writeCurrentUpgrade(t *testing.T, cfg *Config, p upgradetypes.Plan) {
+	t.Helper()
+
+	writeStatusBin(t, cfg.UpgradeBin(p.Name), 0)
+	link := filepath.Join(cfg.Root(), currentLink)
+	require.NoError(t, os.RemoveAll(link))
+	require.NoError(t, os.Symlink(cfg.UpgradeDir(p.Name), link))
+
+	bz, err := json.Marshal(p)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(cfg.CurrentUpgradeFilePath(), bz, 0o600))
+}
+
+// writeUpgradeInfo writes the given plan as the upgrade-info.json of the given config.
+// The file is replaced atomically, a running watcher never reads it half written.
+func writeUpgradeInfo(t *testing.T, cfg *Config, p upgradetypes.Plan) {
+	t.Helper()
+
+	bz, err := json.Marshal(p)
+	require.NoError(t, err)
+	tmp := cfg.UpgradeInfoFilePath() + ".tmp"
+	require.NoError(t, os.WriteFile(tmp, bz, 0o600))
+	require.NoError(t, os.Rename(tmp, cfg.UpgradeInfoFilePath()))
+}
+
+func TestCheckUpdateCurrentUpgradeFile(t *testing.T) {
+	cases := map[string]struct {
+		currentUpgrade *upgradetypes.Plan
+		expectUpdate   bool
+	}{
+		"file present with running upgrade": {
+			currentUpgrade: &upgradetypes.Plan{Name: "chain2", Height: 49},
+			expectUpdate:   false,
+		},
+		"file present with previous upgrade": {
+			currentUpgrade: &upgradetypes.Plan{Name: "chain1", Height: 20},
+			expectUpdate:   true,
+		},
+		"file absent": {
+			currentUpgrade: nil,
+			expectUpdate:   true,
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			cfg := &Config{Home: t.TempDir(), Name: "dummyd"}
+			fw := newTestWatcher(t, cfg)
+			writeUpgradeInfo(t, cfg, upgradetypes.Plan{Name: "chain2", Height: 49})
+
+			if tc.currentUpgrade != nil {
+				writeCurrentUpgrade(t, cfg, *tc.currentUpgrade)
+			}
+
+			require.Equal(t, tc.expectUpdate, fw.CheckUpdate(upgradetypes.Plan{}))
+		})
+	}
+}
+
+func TestCheckUpdateMinFileAge(t *testing.T) {
+	require := require.New(t)
+	cfg := &Config{Home: t.TempDir(), Name: "dummyd", MinFileAge: time.Hour}
+	fw := newTestWatcher(t, cfg)
+
+	// a file which keeps getting rewritten is never acted upon
+	for i := 0; i < 3; i++ {
+		writeUpgradeInfo(t, cfg, upgradetypes.Plan{Name: "chain2", Height: int64(49 + i)})
+		require.False(fw.CheckUpdate(upgradetypes.Plan{}))
+	}
+
+	// once the file has settled, it is acted upon
+	later := time.Now().Add(2 * time.Hour)
+	fw.now = func() time.Time { return later }
+	require.True(fw.CheckUpdate(upgradetypes.Plan{}))
+	require.Equal(int64(51), fw.currentInfo.Height)
+}
+
+func TestParseUpgradeInfoFileSchema(t *testing.T) {
+	dir := filepath.Join(".", "testdata", "upgrade-schema")
+	schema, err := loadUpgradeInfoSchema(filepath.Join(dir, "schema.json"))
+	require.NoError(t, err)
+
+	cases := []struct {
+		filename     string
+		expectErrs   []string
+		expectSchema bool
+	}{
+		{
+			filename: "valid.json",
+		},
+		{
+			filename:     "missing-name.json",
+			expectErrs:   []string{"(root): missing properties: 'name'"},
+			expectSchema: true,
+		},
+		{
+			filename:     "string-height.json",
+			expectErrs:   []string{"height: expected integer, but got string"},
+			expectSchema: true,
+		},
+		{
+			filename:     "out-of-range.json",
+			expectErrs:   []string{"name: length must be >= 1, but got 0", "height: must be >= 1 but found 0"},
+			expectSchema: true,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.filename, func(t *testing.T) {
+			ui, err := ParseUpgradeInfoFile(filepath.Join(dir, tc.filename), ParseOptionSchema(schema))
+			if !tc.expectSchema {
+				require.NoError(t, err)
+				require.Equal(t, upgradetypes.Plan{Name: "upgrade1", Info: "some info", Height: 123}, ui)
+				return
+			}
+
+			require.ErrorIs(t, err, ErrUpgradeInfoSchema)
+			for _, msg := range tc.expectErrs {
+				require.ErrorContains(t, err, msg)
+			}
+		})
+	}
+}
+
+func TestLoadUpgradeInfoSchema(t *testing.T) {
+	schema, err := loadUpgradeInfoSchema("")
+	require.NoError(t, err)
+	require.Nil(t, schema)
+
+	_, err = loadUpgradeInfoSchema(filepath.Join(".", "testdata", "upgrade-schema", "unknown.json"))
+	require.Error(t, err)
+}
+
+func TestCheckUpdateVerifyHaltHeight(t *testing.T) {
+	cases := map[string]struct {
+		haltHeight    int64
+		tolerance     int64
+		expectOverrun bool
+	}{
+		"exact": {
+			haltHeight: 49,
+		},
+		"within tolerance": {
+			haltHeight: 51,
+			tolerance:  2,
+		},
+		"overrun": {
+			haltHeight:    52,
+			tolerance:     2,
+			expectOverrun: true,
+		},
+		"unknown height": {
+			haltHeight: 0,
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			srv := newCallbackRecorder(t)
+			cfg := &Config{
+				Home:                t.TempDir(),
+				Name:                "dummyd",
+				CallbackAPI:         srv.URL,
+				VerifyHeightReached: true,
+				HeightTolerance:     tc.tolerance,
+			}
+			fw := newTestWatcher(t, cfg)
+			heights := []int64{49, tc.haltHeight}
+			fw.getHeight = func() (int64, error) {
+				h := heights[0]
+				heights = heights[1:]
+				return h, nil
+			}
+			writeUpgradeInfo(t, cfg, upgradetypes.Plan{Name: "chain2", Height: 49})
+
+			require.True(t, fw.CheckUpdate(upgradetypes.Plan{}))
+			require.Empty(t, heights)
+
+			overruns := srv.received("/internal/cosmos///" + callbackPaths[CallbackEventHeightOverrun])
+			if !tc.expectOverrun {
+				require.Empty(t, overruns)
+				return
+			}
+
+			require.Len(t, overruns, 1)
+			require.Equal(t, "chain2", overruns[0].Name)
+			require.Equal(t, int64(49), overruns[0].Height)
+			require.Equal(t, tc.haltHeight, overruns[0].CurrentHeight)
+		})
+	}
+}
+
+func TestMonitorUpdatePauseResume(t *testing.T) {
+	require := require.New(t)
+	cfg := &Config{Home: t.TempDir(), Name: "dummyd"}
+	fw := newTestWatcher(t, cfg)
+	defer fw.Stop()
+
+	writeUpgradeInfo(t, cfg, upgradetypes.Plan{Name: "chain2", Height: 49})
+	done := fw.MonitorUpdate(upgradetypes.Plan{Name: "chain2"})
+
+	// the running upgrade is not picked up again
+	time.Sleep(20 * time.Millisecond)
+	fw.Pause()
+	fw.Pause()
+	require.True(fw.initialized)
+	require.Equal(int64(49), fw.currentInfo.Height)
+
+	// a new plan is not acted upon while paused
+	writeUpgradeInfo(t, cfg, upgradetypes.Plan{Name: "chain3", Height: 100})
+	select {
+	case <-done:
+		require.Fail("upgrade signaled while paused")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	// concurrent calls are safe and never leave polling resumed with the ticker stopped
+	for fw.isPaused() {
+		var wg sync.WaitGroup
+		for i := 0; i < 10; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				if i%2 == 0 {
+					fw.Pause()
+				} else {
+					fw.Resume()
+				}
+			}(i)
+		}
+		wg.Wait()
+	}
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		require.Fail("upgrade not signaled after resume")
+	}
+	require.Equal(upgradetypes.Plan{Name: "chain3", Height: 100}, fw.currentInfo)
+}
+
+func TestMonitorUpdateRestartPaused(t *testing.T) {
+	require := require.New(t)
+	cfg := &Config{Home: t.TempDir(), Name: "dummyd"}
+	fw := newTestWatcher(t, cfg)
+	defer fw.Stop()
+
+	// restarting, pausing and resuming concurrently is safe
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			fw.Stop()
+			fw.MonitorUpdate(upgradetypes.Plan{})
+		}()
+		go func() {
+			defer wg.Done()
+			fw.Pause()
+			fw.Resume()
+		}()
+	}
+	wg.Wait()
+
+	// restarting the monitoring loop while paused keeps it paused
+	fw.Pause()
+	writeUpgradeInfo(t, cfg, upgradetypes.Plan{Name: "chain2", Height: 49})
+	fw.Stop()
+	done := fw.MonitorUpdate(upgradetypes.Plan{})
+	select {
+	case <-done:
+		require.Fail("upgrade signaled while paused")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	fw.Resume()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		require.Fail("upgrade not signaled after resume")
+	}
+	require.Equal(upgradetypes.Plan{Name: "chain2", Height: 49}, fw.currentInfo)
+}
+
+func TestMonitorUpdateInvalidFile(t *testing.T) {
+	require := require.New(t)
+	srv := newCallbackRecorder(t)
+	cfg := &Config{Home: t.TempDir(), Name: "dummyd", CallbackAPI: srv.URL}
+	fw := newTestWatcher(t, cfg)
+	defer fw.Stop()
+	invalid := "/internal/cosmos///" + callbackPaths[CallbackEventValidationFailed]
+
+	tmp := cfg.UpgradeInfoFilePath() + ".tmp"
+	require.NoError(os.WriteFile(tmp, []byte(`{"name":"chain2","height":`), 0o600))
+	require.NoError(os.Rename(tmp, cfg.UpgradeInfoFilePath()))
+	done := fw.MonitorUpdate(upgradetypes.Plan{})
+
+	// the invalid file is reported once and the watcher keeps running
+	require.Eventually(func() bool { return len(srv.received(invalid)) > 0 }, time.Second, time.Millisecond)
+	select {
+	case <-done:
+		require.Fail("upgrade signaled for an invalid file")
+	case <-time.After(20 * time.Millisecond):
+	}
+	require.Len(srv.received(invalid), 1)
+
+	writeUpgradeInfo(t, cfg, upgradetypes.Plan{Name: "chain2", Height: 49})
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		require.Fail("upgrade not signaled once the file is valid")
+	}
+	require.Equal(upgradetypes.Plan{Name: "chain2", Height: 49}, fw.currentInfo)
+}
+
+func TestPreferredPlatforms(t *testing.T) {
+	binaries := map[string]BinaryRef{
+		"linux/amd64":  {},
+		"linux/arm64":  {},
+		"darwin/arm64": {},
+		"any":          {},
+	}
+
+	cases := map[string]struct {
+		preference []string
+		binaries   map[string]BinaryRef
+		expect     []string
+	}{
+		"default": {
+			binaries: binaries,
+			expect:   []string{OSArch(), "any", "darwin/arm64", "linux/arm64"},
+		},
+		"ordered": {
+			preference: []string{"linux/arm64", "linux/amd64", "any"},
+			binaries:   binaries,
+			expect:     []string{"linux/arm64", "linux/amd64", "any", "darwin/arm64"},
+		},
+		"falls through missing platforms": {
+			preference: []string{"windows/amd64", "linux/amd64"},
+			binaries:   binaries,
+			expect:     []string{"linux/amd64", "any", "darwin/arm64", "linux/arm64"},
+		},
+		"none preferred": {
+			preference: []string{"windows/amd64"},
+			binaries:   map[string]BinaryRef{"linux/arm64": {}, "darwin/arm64": {}},
+			expect:     []string{"darwin/arm64", "linux/arm64"},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			require.Equal(t, tc.expect, preferredPlatforms(tc.binaries, tc.preference))
+		})
+	}
+}
+
+func TestCheckUpdatePlatformPreference(t *testing.T) {
+	srv := newCallbackRecorder(t)
+	cfg := &Config{
+		Home:               t.TempDir(),
+		Name:               "dummyd",
+		CallbackAPI:        srv.URL,
+		PlatformPreference: []string{"linux/arm64", "linux/amd64"},
+	}
+	fw := newTestWatcher(t, cfg)
+	fw.getHeight = func() (int64, error) { return 10, nil }
+	writeUpgradeInfo(t, cfg, upgradetypes.Plan{Name: "chain2", Height: 49, Info: `{"binaries":{
+		"linux/amd64":"https://github.com/org/amd64d/releases/download/v2.0.0/chaind",
+		"linux/arm64":"https://github.com/org/arm64d/releases/download/v2.0.1/chaind",
+		"any":"https://github.com/org/anyd/releases/download/v2.0.2/chaind"
+	}}`})
+
+	require.False(t, fw.CheckUpdate(upgradetypes.Plan{}))
+
+	detected := srv.received("/internal/cosmos///" + callbackPaths[CallbackEventDetected])
+	require.Len(t, detected, 1)
+	require.Equal(t, "v2.0.1", detected[0].Version)
+	require.Equal(t, "https://github.com/org/arm64d", detected[0].Repo)
+}
+
+func TestCheckUpdatePlanAmended(t *testing.T) {
+	srv := newCallbackRecorder(t)
+	cfg := &Config{Home: t.TempDir(), Name: "dummyd", CallbackAPI: srv.URL}
+	fw := newTestWatcher(t, cfg)
+	fw.getHeight = func() (int64, error) { return 49, nil }
+	amended := "/internal/cosmos///" + callbackPaths[CallbackEventPlanAmended]
+
+	// rewrite bumps the modification time so the file is read again
+	modTime := time.Now()
+	rewrite := func(p upgradetypes.Plan) {
+		writeUpgradeInfo(t, cfg, p)
+		modTime = modTime.Add(time.Second)
+		require.NoError(t, os.Chtimes(cfg.UpgradeInfoFilePath(), modTime, modTime))
+		// the upgrade was applied and a new monitoring round started
+		fw.needsUpdate = false
+	}
+
+	plan := upgradetypes.Plan{Name: "chain2", Height: 49, Info: "https://example.com/v1"}
+	rewrite(plan)
+	require.True(t, fw.CheckUpdate(upgradetypes.Plan{}))
+
+	// an identical rewrite is ignored
+	rewrite(plan)
+	require.False(t, fw.CheckUpdate(upgradetypes.Plan{Name: "chain2"}))
+	require.Empty(t, srv.received(amended))
+
+	// a corrected plan at the same height triggers again
+	plan.Info = "https://example.com/v2"
+	rewrite(plan)
+	require.True(t, fw.CheckUpdate(upgradetypes.Plan{Name: "chain2"}))
+	require.Equal(t, plan, fw.currentInfo)
+	got := srv.received(amended)
+	require.Len(t, got, 1)
+	require.Equal(t, "https://example.com/v2", got[0].Info)
+
+	// so does a renamed one
+	plan.Name = "chain2-fix"
+	rewrite(plan)
+	require.True(t, fw.CheckUpdate(upgradetypes.Plan{Name: "chain2"}))
+	require.Len(t, srv.received(amended), 2)
+	require.Len(t, srv.received("/internal/cosmos///"+callbackPaths[CallbackEventReached]), 3)
+}
+
+func TestCheckUpdateMaxUpgradeSignals(t *testing.T) {
+	srv := newCallbackRecorder(t)
+	cfg := &Config{Home: t.TempDir(), Name: "dummyd", CallbackAPI: srv.URL, MaxUpgradeSignals: 2}
+	failed := "/internal/cosmos///" + callbackPaths[CallbackEventUpgradeFailed]
+
+	// every restart signals the same upgrade again, the count surviving restarts
+	restart := func() *fileWatcher {
+		fw := newTestWatcher(t, cfg)
+		fw.getHeight = func() (int64, error) { return 49, nil }
+		return fw
+	}
+
+	fw := restart()
+	writeUpgradeInfo(t, cfg, upgradetypes.Plan{Name: "chain2", Height: 49})
+	require.True(t, fw.CheckUpdate(upgradetypes.Plan{}))
+	require.True(t, restart().CheckUpdate(upgradetypes.Plan{}))
+	require.Empty(t, srv.received(failed))
+
+	fw = restart()
+	require.False(t, fw.CheckUpdate(upgradetypes.Plan{}))
+	require.False(t, fw.needsUpdate)
+	got := srv.received(failed)
+	require.Len(t, got, 1)
+	require.Equal(t, "chain2", got[0].Name)
+	require.Equal(t, int64(49), got[0].Height)
+	require.Contains(t, got[0].Error, "signaled 2 times")
+
+	state, err := loadWatcherState(cfg.StateFilePath())
+	require.NoError(t, err)
+	require.Equal(t, &upgradeSignal{Name: "chain2", Height: 49, Count: 2}, state.LastSignal)
+
+	// another upgrade starts a new count
+	writeUpgradeInfo(t, cfg, upgradetypes.Plan{Name: "chain3", Height: 49})
+	require.True(t, restart().CheckUpdate(upgradetypes.Plan{}))
+	state, err = loadWatcherState(cfg.StateFilePath())
+	require.NoError(t, err)
+	require.Equal(t, &upgradeSignal{Name: "chain3", Height: 49, Count: 1}, state.LastSignal)
+}
+
+func TestLoadWatcherState(t *testing.T) {
+	path := filepath.Join(t.TempDir(), stateFilename)
+
+	state, err := loadWatcherState(path)
+	require.NoError(t, err)
+	require.Equal(t, watcherState{}, state)
+
+	require.NoError(t, os.WriteFile(path, []byte("{"), 0o600))
+	_, err = loadWatcherState(path)
+	require.Error(t, err)
+}
+
+func TestStopWatcherStoppedCallback(t *testing.T) {
+	srv := newCallbackRecorder(t)
+	stopped := "/internal/cosmos///" + callbackPaths[CallbackEventWatcherStopped]
+
+	for _, enabled := range []bool{false, true} {
+		cfg := &Config{Home: t.TempDir(), Name: "dummyd", CallbackAPI: srv.URL, CallbackWatcherStopped: enabled}
+		fw := newTestWatcher(t, cfg)
+		fw.getHeight = func() (int64, error) { return 40, nil }
+		writeUpgradeInfo(t, cfg, upgradetypes.Plan{Name: "chain2", Height: 49})
+		require.False(t, fw.CheckUpdate(upgradetypes.Plan{}))
+
+		fw.stop(errors.New("exit status 1"))
+		if !enabled {
+			require.Empty(t, srv.received(stopped))
+			continue
+		}
+
+		got := srv.received(stopped)
+		require.Len(t, got, 1)
+		require.Equal(t, "chain2", got[0].Name)
+		require.Equal(t, int64(49), got[0].Height)
+		require.Equal(t, int64(40), got[0].CurrentHeight)
+		require.Equal(t, "exit status 1", got[0].Error)
+	}
+}
+
+func TestWaitForUpgradeOrExitWatcherStopped(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the app is a unix shell command")
+	}
+
+	for _, upgrade := range []bool{false, true} {
+		srv := newCallbackRecorder(t)
+		stopped := "/internal/cosmos///" + callbackPaths[CallbackEventWatcherStopped]
+		cfg := &Config{Home: t.TempDir(), Name: "dummyd", PollInterval: time.Hour, CallbackAPI: srv.URL, CallbackWatcherStopped: true}
+		fw := newTestWatcher(t, cfg)
+		fw.getHeight = func() (int64, error) { return 49, nil }
+		if upgrade {
+			writeUpgradeInfo(t, cfg, upgradetypes.Plan{Name: "chain2", Height: 49})
+		}
+
+		// the app halts before the upgrade is picked up, it is only reported stopped without one
+		l := Launcher{logger: log.NewNopLogger(), cfg: cfg, fw: fw}
+		cmd := exec.Command("sh", "-c", "exit 1")
+		require.NoError(t, cmd.Start())
+		needsUpdate, err := l.WaitForUpgradeOrExit(cmd)
+		require.Equal(t, upgrade, needsUpdate)
+		if upgrade {
+			require.NoError(t, err)
+			require.Empty(t, srv.received(stopped))
+			continue
+		}
+
+		require.Error(t, err)
+		got := srv.received(stopped)
+		require.Len(t, got, 1)
+		require.Equal(t, "exit status 1", got[0].Error)
+	}
+}
+
+func TestStopWatcherStoppedUnreachable(t *testing.T) {
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) { <-release }))
+	defer srv.Close()
+	defer close(release)
+
+	defer func(timeout time.Duration) { watcherStoppedTimeout = timeout }(watcherStoppedTimeout)
+	watcherStoppedTimeout = 50 * time.Millisecond
+
+	cfg := &Config{Home: t.TempDir(), Name: "dummyd", CallbackAPI: srv.URL, CallbackWatcherStopped: true}
+	fw := newTestWatcher(t, cfg)
+
+	start := time.Now()
+	fw.Stop()
+	require.Less(t, time.Since(start), time.Second)
+}
+
+func TestCheckUpdateChangeDetection(t *testing.T) {
+	cases := map[string]struct {
+		detection    string
+		expectUpdate bool
+	}{
+		"modtime misses mtime-preserving rewrites": {detection: ChangeDetectionModTime},
+		"hash": {detection: ChangeDetectionHash, expectUpdate: true},
+		"both": {detection: ChangeDetectionBoth, expectUpdate: true},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			cfg := &Config{Home: t.TempDir(), Name: "dummyd", ChangeDetection: tc.detection}
+			fw := newTestWatcher(t, cfg)
+			fw.getHeight = func() (int64, error) { return 60, nil }
+
+			mtime := time.Now().Add(-time.Hour)
+			rewrite := func(p upgradetypes.Plan) {
+				writeUpgradeInfo(t, cfg, p)
+				require.NoError(t, os.Chtimes(cfg.UpgradeInfoFilePath(), mtime, mtime))
+				fw.needsUpdate = false
+			}
+
+			rewrite(upgradetypes.Plan{Name: "chain2", Height: 49})
+			require.True(t, fw.CheckUpdate(upgradetypes.Plan{}))
+
+			// an identical rewrite is never an upgrade
+			rewrite(upgradetypes.Plan{Name: "chain2", Height: 49})
+			require.False(t, fw.CheckUpdate(upgradetypes.Plan{Name: "chain2"}))
+
+			// a new plan keeping the modification time
+			rewrite(upgradetypes.Plan{Name: "chain3", Height: 59})
+			require.Equal(t, tc.expectUpdate, fw.CheckUpdate(upgradetypes.Plan{Name: "chain2"}))
+		})
+	}
+}
+
+func TestMonitorUpdateConcurrent(t *testing.T) {
+	srv := newCallbackRecorder(t)
+	cfg := &Config{Home: t.TempDir(), Name: "dummyd", CallbackAPI: srv.URL}
+	fw := newTestWatcher(t, cfg)
+	fw.getHeight = func() (int64, error) { return 49, nil }
+
+	var wg sync.WaitGroup
+	channels := make([]<-chan struct{}, 10)
+	for i := range channels {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			channels[i] = fw.MonitorUpdate(upgradetypes.Plan{})
+		}(i)
+	}
+	wg.Wait()
+
+	// a single loop runs and every caller shares its channel
+	for _, ch := range channels {
+		require.Equal(t, channels[0], ch)
+	}
+
+	writeUpgradeInfo(t, cfg, upgradetypes.Plan{Name: "chain2", Height: 49})
+	for _, ch := range channels {
+		select {
+		case <-ch:
+		case <-time.After(time.Second):
+			require.Fail(t, "upgrade not signaled")
+		}
+	}
+	require.Len(t, srv.received("/internal/cosmos///"+callbackPaths[CallbackEventReached]), 1)
+
+	// a new loop can be started once the previous one finished
+	require.Eventually(t, func() bool {
+		fw.monitorMu.Lock()
+		defer fw.monitorMu.Unlock()
+		return fw.monitorDone == nil
+	}, time.Second, time.Millisecond)
+	next := fw.MonitorUpdate(upgradetypes.Plan{Name: "chain2"})
+	require.NotEqual(t, channels[0], next)
+	fw.Stop()
+}
+
+func TestStopWaitsForMonitorUpdate(t *testing.T) {
+	cfg := &Config{Home: t.TempDir(), Name: "dummyd"}
+	fw := newTestWatcher(t, cfg)
+
+	checking, release := make(chan struct{}), make(chan struct{})
+	var once sync.Once
+	fw.getHeight = func() (int64, error) {
+		once.Do(func() { close(checking) })
+		<-release
+		return 40, nil
+	}
+	writeUpgradeInfo(t, cfg, upgradetypes.Plan{Name: "chain2", Height: 49})
+	first := fw.MonitorUpdate(upgradetypes.Plan{})
+	<-checking
+
+	// the loop is still checking for an upgrade when stopped
+	stopped := make(chan struct{})
+	go func() {
+		fw.Stop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+		require.Fail(t, "stop returned before the monitoring loop exited")
+	case <-time.After(20 * time.Millisecond):
+	}
+	close(release)
+	<-stopped
+
+	// a relaunch starts a new loop rather than getting the stale channel
+	next := fw.MonitorUpdate(upgradetypes.Plan{})
+	require.NotEqual(t, first, next)
+	fw.Stop()
+}
+
+func TestStopTwice(t *testing.T) {
+	cfg := &Config{Home: t.TempDir(), Name: "dummyd"}
+	fw := newTestWatcher(t, cfg)
+	fw.getHeight = func() (int64, error) { return 40, nil }
+
+	// stopping a watcher which never monitored
+	fw.Stop()
+
+	fw.MonitorUpdate(upgradetypes.Plan{})
+	fw.Stop()
+	fw.Stop()
+
+	// the app exiting stops the loop before the watcher itself is stopped
+	fw.MonitorUpdate(upgradetypes.Plan{})
+	fw.stopMonitor()
+	fw.Stop()
+}
+
+func TestMonitorUpdateStopConcurrent(t *testing.T) {
+	cfg := &Config{Home: t.TempDir(), Name: "dummyd"}
+	fw := newTestWatcher(t, cfg)
+	fw.getHeight = func() (int64, error) { return 40, nil }
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			fw.MonitorUpdate(upgradetypes.Plan{})
+		}()
+		go func() {
+			defer wg.Done()
+			fw.Stop()
+		}()
+	}
+	wg.Wait()
+
+	fw.Stop()
+	fw.monitorMu.Lock()
+	defer fw.monitorMu.Unlock()
+	require.Nil(t, fw.monitorDone)
+	require.Nil(t, fw.monitorCancel)
+}
+
+func TestEstimateProgress(t *testing.T) {
+	start := time.Unix(1000, 0)
+	cases := map[string]struct {
+		prev, cur heightSample
+		target    int64
+		expect    upgradeProgress
+	}{
+		"steady block rate": {
+			prev:   heightSample{height: 100, at: start},
+			cur:    heightSample{height: 110, at: start.Add(50 * time.Second)},
+			target: 170,
+			expect: upgradeProgress{BlocksRemaining: 60, BlockTime: 5 * time.Second, ETA: 5 * time.Minute},
+		},
+		"height not advanced": {
+			prev:   heightSample{height: 100, at: start},
+			cur:    heightSample{height: 100, at: start.Add(time.Minute)},
+			target: 170,
+			expect: upgradeProgress{BlocksRemaining: 70},
+		},
+		"unknown previous height": {
+			prev:   heightSample{},
+			cur:    heightSample{height: 100, at: start},
+			target: 170,
+			expect: upgradeProgress{BlocksRemaining: 70},
+		},
+		"target passed": {
+			prev:   heightSample{height: 100, at: start},
+			cur:    heightSample{height: 180, at: start.Add(time.Minute)},
+			target: 170,
+			expect: upgradeProgress{BlockTime: 750 * time.Millisecond},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			require.Equal(t, tc.expect, estimateProgress(tc.prev, tc.cur, tc.target))
+		})
+	}
+}
+
+func TestCheckUpdateUpgradePending(t *testing.T) {
+	srv := newCallbackRecorder(t)
+	cfg := &Config{Home: t.TempDir(), Name: "dummyd", CallbackAPI: srv.URL, CallbackUpgradePending: true}
+	fw := newTestWatcher(t, cfg)
+	pending := "/internal/cosmos///" + callbackPaths[CallbackEventUpgradePending]
+	writeUpgradeInfo(t, cfg, upgradetypes.Plan{Name: "chain2", Height: 1000})
+
+	now := time.Unix(0, 0)
+	height := int64(400)
+	fw.now = func() time.Time { return now }
+	fw.getHeight = func() (int64, error) { return height, nil }
+
+	// the first sample only starts measuring the block rate
+	require.False(t, fw.CheckUpdate(upgradetypes.Plan{}))
+	require.Empty(t, srv.received(pending))
+
+	// not reported again within the interval
+	now = now.Add(30 * time.Second)
+	height += 5
+	require.False(t, fw.CheckUpdate(upgradetypes.Plan{}))
+	require.Empty(t, srv.received(pending))
+
+	// 12 blocks per minute leaves 49 minutes for the remaining 588 blocks
+	now = now.Add(30 * time.Second)
+	height += 7
+	require.False(t, fw.CheckUpdate(upgradetypes.Plan{}))
+	got := srv.received(pending)
+	require.Len(t, got, 1)
+	require.Equal(t, "chain2", got[0].Name)
+	require.Equal(t, int64(1000), got[0].Height)
+	require.Equal(t, int64(412), got[0].CurrentHeight)
+	require.Equal(t, int64(588), got[0].BlocksRemaining)
+	require.Equal(t, int64(588*5), got[0].ETASeconds)
+	require.Equal(t, reasonHeightNotReached, got[0].Reason)
+
+	// the block rate is measured over the last interval only
+	now = now.Add(time.Minute)
+	height += 30
+	require.False(t, fw.CheckUpdate(upgradetypes.Plan{}))
+	got = srv.received(pending)
+	require.Len(t, got, 2)
+	require.Equal(t, int64(558), got[1].BlocksRemaining)
+	require.Equal(t, int64(558*2), got[1].ETASeconds)
+}
+
+func TestCheckUpdateImminent(t *testing.T) {
+	require := require.New(t)
+	srv := newCallbackRecorder(t)
+	cfg := &Config{Home: t.TempDir(), Name: "dummyd", CallbackAPI: srv.URL, CallbackImminentBlocks: 10}
+	fw := newTestWatcher(t, cfg)
+	imminent := "/internal/cosmos///" + callbackPaths[CallbackEventImminent]
+	writeUpgradeInfo(t, cfg, upgradetypes.Plan{Name: "chain2", Height: 100})
+
+	height := int64(89)
+	fw.getHeight = func() (int64, error) { return height, nil }
+	require.False(fw.CheckUpdate(upgradetypes.Plan{}))
+	require.Empty(srv.received(imminent))
+
+	// sent once as the node comes within the threshold
+	for _, h := range []int64{90, 95, 99} {
+		height = h
+		require.False(fw.CheckUpdate(upgradetypes.Plan{}))
+	}
+	got := srv.received(imminent)
+	require.Len(got, 1)
+	require.Equal("chain2", got[0].Name)
+	require.Equal(int64(100), got[0].Height)
+	require.Equal(int64(90), got[0].CurrentHeight)
+	require.Equal(int64(10), got[0].BlocksRemaining)
+
+	// and once more for a new plan
+	writeUpgradeInfo(t, cfg, upgradetypes.Plan{Name: "chain2", Height: 105})
+	require.False(fw.CheckUpdate(upgradetypes.Plan{}))
+	require.Len(srv.received(imminent), 2)
+}
+
+func TestCheckUpdateUnreadable(t *testing.T) {
+	srv := newCallbackRecorder(t)
+	cfg := &Config{Home: t.TempDir(), Name: "dummyd", CallbackAPI: srv.URL}
+	fw := newTestWatcher(t, cfg)
+	fw.getHeight = func() (int64, error) { return 49, nil }
+	unreadable := "/internal/cosmos///" + callbackPaths[CallbackEventInfoUnreadable]
+	writeUpgradeInfo(t, cfg, upgradetypes.Plan{Name: "chain2", Height: 49})
+
+	// the file can still be stat'ed, only reading it fails
+	require.NoError(t, os.Chmod(cfg.UpgradeInfoFilePath(), 0o200))
+	t.Cleanup(func() { _ = os.Chmod(cfg.UpgradeInfoFilePath(), 0o600) })
+	if _, err := os.ReadFile(cfg.UpgradeInfoFilePath()); err == nil {
+		t.Skip("file permissions are not enforced, e.g. when running as root")
+	}
+
+	now := time.Unix(0, 0)
+	fw.now = func() time.Time { return now }
+
+	_, err := ParseUpgradeInfoFile(cfg.UpgradeInfoFilePath())
+	require.ErrorIs(t, err, ErrUpgradeInfoUnreadable)
+	require.ErrorIs(t, err, fs.ErrPermission)
+
+	require.NotPanics(t, func() { require.False(t, fw.CheckUpdate(upgradetypes.Plan{})) })
+	got := srv.received(unreadable)
+	require.Len(t, got, 1)
+	require.Contains(t, got[0].Error, ErrUpgradeInfoUnreadable.Error())
+
+	// the alert is throttled
+	now = now.Add(30 * time.Second)
+	require.False(t, fw.CheckUpdate(upgradetypes.Plan{}))
+	require.Len(t, srv.received(unreadable), 1)
+	now = now.Add(30 * time.Second)
+	require.False(t, fw.CheckUpdate(upgradetypes.Plan{}))
+	require.Len(t, srv.received(unreadable), 2)
+
+	// the upgrade is picked up once the permissions are fixed
+	require.NoError(t, os.Chmod(cfg.UpgradeInfoFilePath(), 0o600))
+	require.True(t, fw.CheckUpdate(upgradetypes.Plan{}))
+	require.Equal(t, "chain2", fw.currentInfo.Name)
+	require.True(t, fw.unreadableAlerted.IsZero())
+}
+
+func TestCheckUpdateExpandInfoUndefined(t *testing.T) {
+	require := require.New(t)
+	srv := newCallbackRecorder(t)
+	cfg := &Config{Home: t.TempDir(), Name: "dummyd", CallbackAPI: srv.URL, ExpandInfoEnv: true}
+	fw := newTestWatcher(t, cfg)
+	fw.getHeight = func() (int64, error) { return 49, nil }
+	invalid := "/internal/cosmos///" + callbackPaths[CallbackEventValidationFailed]
+
+	// a variable missing on the host is a validation failure, not a crash
+	plan := upgradetypes.Plan{Name: "chain2", Height: 49, Info: "${BASE_URL}/chaind"}
+	writeUpgradeInfo(t, cfg, plan)
+	require.NotPanics(func() { require.False(fw.CheckUpdate(upgradetypes.Plan{})) })
+	got := srv.received(invalid)
+	require.Len(got, 1)
+	require.Contains(got[0].Error, "undefined variables in info: BASE_URL")
+
+	// not reported again until the file changes
+	require.False(fw.CheckUpdate(upgradetypes.Plan{}))
+	require.Len(srv.received(invalid), 1)
+
+	t.Setenv(EnvInfoVarPrefix+"BASE_URL", "https://mirror.example.com")
+	writeUpgradeInfo(t, cfg, plan)
+	later := time.Now().Add(time.Second)
+	require.NoError(os.Chtimes(cfg.UpgradeInfoFilePath(), later, later))
+	require.True(fw.CheckUpdate(upgradetypes.Plan{}))
+	require.Equal("https://mirror.example.com/chaind", fw.currentInfo.Info)
+}
+
+func TestCheckUpdateRollback(t *testing.T) {
+	srv := newCallbackRecorder(t)
+	cfg := &Config{Home: t.TempDir(), Name: "dummyd", CallbackAPI: srv.URL}
+	fw := newTestWatcher(t, cfg)
+	fw.getHeight = func() (int64, error) { return 0, nil }
+	rollback := "/internal/cosmos///" + callbackPaths[CallbackEventRollbackDetected]
+
+	modTime := time.Now()
+	rewrite := func(p upgradetypes.Plan) {
+		writeUpgradeInfo(t, cfg, p)
+		modTime = modTime.Add(time.Second)
+		require.NoError(t, os.Chtimes(cfg.UpgradeInfoFilePath(), modTime, modTime))
+		fw.needsUpdate = false
+	}
+
+	rewrite(upgradetypes.Plan{Name: "chain3", Height: 90})
+	require.True(t, fw.CheckUpdate(upgradetypes.Plan{Name: "chain2"}))
+
+	// reverting to an earlier plan resets the tracked upgrade without upgrading
+	rewrite(upgradetypes.Plan{Name: "chain2", Height: 49})
+	require.False(t, fw.CheckUpdate(upgradetypes.Plan{Name: "chain3"}))
+	require.Equal(t, upgradetypes.Plan{Name: "chain2", Height: 49}, fw.currentInfo)
+	got := srv.received(rollback)
+	require.Len(t, got, 1)
+	require.Equal(t, "chain2", got[0].Name)
+	require.Equal(t, int64(49), got[0].Height)
+	require.Equal(t, "chain3", got[0].PreviousName)
+	require.Equal(t, int64(90), got[0].PreviousHeight)
+
+	// the rolled back plan is not reported again
+	require.False(t, fw.CheckUpdate(upgradetypes.Plan{Name: "chain3"}))
+	require.Len(t, srv.received(rollback), 1)
+
+	// a fresh upgrade above the rolled back height is triggered, including one at the height
+	// tracked before the rollback
+	rewrite(upgradetypes.Plan{Name: "chain3-fix", Height: 90})
+	require.True(t, fw.CheckUpdate(upgradetypes.Plan{Name: "chain2"}))
+	require.Equal(t, "chain3-fix", fw.currentInfo.Name)
+	require.Len(t, srv.received(rollback), 1)
+}
+
+func TestCheckUpdateVerifyAppVersion(t *testing.T) {
+	info := `{"binaries":{"any":"https://github.com/cosmos/gaia/releases/download/v2.0.0/gaiad"}}`
+	notGreater := "is not greater than the app version"
+	cases := map[string]struct {
+		appVersion    string
+		appVersionErr error
+		info          string
+		expectUpgrade bool
+		expectErr     string
+	}{
+		"lower app version":  {appVersion: "v1.4.2", info: info, expectUpgrade: true},
+		"equal app version":  {appVersion: "v2.0.0", info: info, expectErr: notGreater},
+		"higher app version": {appVersion: "2.1.0", info: info, expectErr: notGreater},
+		"unknown app version": {
+			appVersionErr: errors.New("unknown command \"version\""),
+			info:          info,
+			expectUpgrade: true,
+		},
+		"unparseable version output": {
+			appVersionErr: fmt.Errorf("%w: invalid character 'v'", errAppVersionUnparseable),
+			info:          info,
+			expectErr:     "unparseable app version",
+		},
+		"invalid app version": {appVersion: "dev-build", info: info, expectErr: "unparseable app version \"dev-build\""},
+		"no plan version":     {appVersion: "v2.0.0", expectUpgrade: true},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			srv := newCallbackRecorder(t)
+			cfg := &Config{Home: t.TempDir(), Name: "dummyd", CallbackAPI: srv.URL, VerifyAppVersion: true}
+			fw := newTestWatcher(t, cfg)
+			fw.getHeight = func() (int64, error) { return 49, nil }
+			fw.getAppVersion = func() (string, error) { return tc.appVersion, tc.appVersionErr }
+			writeUpgradeInfo(t, cfg, upgradetypes.Plan{Name: "chain2", Height: 49, Info: tc.info})
+
+			require.Equal(t, tc.expectUpgrade, fw.CheckUpdate(upgradetypes.Plan{}))
+
+			invalid := srv.received("/internal/cosmos///" + callbackPaths[CallbackEventValidationFailed])
+			if tc.expectUpgrade {
+				require.Empty(t, invalid)
+				return
+			}
+			require.Len(t, invalid, 1)
+			require.Equal(t, "v2.0.0", invalid[0].Version)
+			require.Contains(t, invalid[0].Error, tc.expectErr)
+			require.Empty(t, srv.received("/internal/cosmos///"+callbackPaths[CallbackEventReached]))
+
+			// the rejected plan is not acted upon again
+			require.False(t, fw.CheckUpdate(upgradetypes.Plan{}))
+			require.Len(t, srv.received("/internal/cosmos///"+callbackPaths[CallbackEventValidationFailed]), 1)
+		})
+	}
+}
+
+func TestQueryAppVersion(t *testing.T) {
+	cases := map[string]struct {
+		output    string
+		expect    string
+		expectErr bool
+	}{
+		"version info": {
+			output: `{"name":"dummy","server_name":"dummyd","version":"v1.4.2","commit":"abc"}`,
+			expect: "v1.4.2",
+		},
+		"plain version":         {output: "v1.4.2", expectErr: true},
+		"trailing output":       {output: `{"version":"v1.4.2"}` + "\nwarning: deprecated flag", expectErr: true},
+		"leading output":        {output: "warning: deprecated flag\n" + `{"version":"v1.4.2"}`, expectErr: true},
+		"empty version":         {output: `{"name":"dummy","version":""}`, expectErr: true},
+		"no output":             {output: "", expectErr: true},
+		"version is not a text": {output: `{"version":2}`, expectErr: true},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			bin := filepath.Join(t.TempDir(), "dummyd")
+			// like cobra, the version is printed to stderr
+			script := fmt.Sprintf("#!/bin/sh\n[ \"$*\" = \"version --long --output json\" ] || exit 1\nprintf '%%s' '%s' >&2\n", tc.output)
+			require.NoError(t, os.WriteFile(bin, []byte(script), 0o755)) //nolint:gosec // the fake binary must be executable
+			fw := &fileWatcher{resolveBin: func() (string, error) { return bin, nil }}
+
+			ver, err := fw.queryAppVersion()
+			if tc.expectErr {
+				require.ErrorIs(t, err, errAppVersionUnparseable)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.expect, ver)
+		})
+	}
+}
+
+func TestMonitorUpdateScanOnStart(t *testing.T) {
+	cases := map[string]struct {
+		scanOnStart    bool
+		currentUpgrade upgradetypes.Plan
+		expectUpgrade  bool
+	}{
+		"pending upgrade detected immediately": {scanOnStart: true, expectUpgrade: true},
+		"applied upgrade":                      {scanOnStart: true, currentUpgrade: upgradetypes.Plan{Name: "chain2"}},
+		"waits for the first tick":             {scanOnStart: false},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			// the poll interval is too long for a tick to detect the upgrade
+			cfg := &Config{Home: t.TempDir(), Name: "dummyd", PollInterval: time.Hour, ScanOnStart: tc.scanOnStart}
+			fw := newTestWatcher(t, cfg)
+			defer fw.Stop()
+			fw.getHeight = func() (int64, error) { return 49, nil }
+			writeUpgradeInfo(t, cfg, upgradetypes.Plan{Name: "chain2", Height: 49})
+
+			select {
+			case <-fw.MonitorUpdate(tc.currentUpgrade):
+				require.True(t, tc.expectUpgrade, "unexpected upgrade")
+				require.Equal(t, "chain2", fw.currentInfo.Name)
+			case <-time.After(100 * time.Millisecond):
+				require.False(t, tc.expectUpgrade, "upgrade not signaled")
+			}
+		})
+	}
+}
+
+func TestCheckUpdateHeightMilestones(t *testing.T) {
+	srv := newCallbackRecorder(t)
+	cfg := &Config{Home: t.TempDir(), Name: "dummyd", CallbackAPI: srv.URL, HeightMilestones: []int64{1000, 100, 10}}
+	fw := newTestWatcher(t, cfg)
+	milestones := "/internal/cosmos///" + callbackPaths[CallbackEventMilestone]
+
+	var height int64
+	fw.getHeight = func() (int64, error) { return height, nil }
+	fired := func() []int64 {
+		var offsets []int64
+		for _, info := range srv.received(milestones) {
+			offsets = append(offsets, info.Milestone)
+		}
+		return offsets
+	}
+
+	writeUpgradeInfo(t, cfg, upgradetypes.Plan{Name: "chain2", Height: 5000})
+	for _, h := range []int64{3000, 4000, 4001, 4500, 4899} {
+		height = h
+		require.False(t, fw.CheckUpdate(upgradetypes.Plan{}))
+	}
+	require.Equal(t, []int64{1000}, fired())
+
+	// milestones skipped over fire in order
+	height = 4995
+	require.False(t, fw.CheckUpdate(upgradetypes.Plan{}))
+	require.Equal(t, []int64{1000, 100, 10}, fired())
+	got := srv.received(milestones)
+	require.Equal(t, "chain2", got[2].Name)
+	require.Equal(t, int64(4995), got[2].CurrentHeight)
+
+	height = 4999
+	require.False(t, fw.CheckUpdate(upgradetypes.Plan{}))
+	require.Len(t, fired(), 3)
+
+	// the milestones fired are not fired again after a restart
+	fw = newTestWatcher(t, cfg)
+	fw.getHeight = func() (int64, error) { return height, nil }
+	require.False(t, fw.CheckUpdate(upgradetypes.Plan{}))
+	require.Len(t, fired(), 3)
+
+	// a new plan starts the countdown over
+	writeUpgradeInfo(t, cfg, upgradetypes.Plan{Name: "chain3", Height: 5050})
+	require.False(t, fw.CheckUpdate(upgradetypes.Plan{}))
+	require.Equal(t, []int64{1000, 100, 10, 1000, 100}, fired())
+	require.Equal(t, "chain3", srv.received(milestones)[4].Name)
+}
+
+func TestParseHeightMilestones(t *testing.T) {
+	milestones, err := parseHeightMilestones("10, 1000,,100,10")
+	require.NoError(t, err)
+	require.Equal(t, []int64{1000, 100, 10}, milestones)
+	require.Equal(t, "1000,100,10", formatHeightMilestones(milestones))
+
+	_, err = parseHeightMilestones("1000,0")
+	require.Error(t, err)
+	_, err = parseHeightMilestones("-10")
+	require.Error(t, err)
+	_, err = parseHeightMilestones("soon")
+	require.Error(t, err)
+}
+
+func TestCheckUpdateUpgradeInfoGlob(t *testing.T) {
+	require := require.New(t)
+
+	cfg := &Config{Home: t.TempDir(), Name: "dummyd", UpgradeInfoGlob: "upgrade-info-*.json"}
+	fw := newTestWatcher(t, cfg)
+	var height int64
+	fw.getHeight = func() (int64, error) { return height, nil }
+
+	dir := filepath.Dir(cfg.UpgradeInfoFilePath())
+	write := func(name string, p upgradetypes.Plan) string {
+		bz, err := json.Marshal(p)
+		require.NoError(err)
+		path := filepath.Join(dir, name)
+		require.NoError(os.WriteFile(path, bz, 0o600))
+		return path
+	}
+	v2 := write("upgrade-info-v2.json", upgradetypes.Plan{Name: "chain2", Height: 100})
+	v3 := write("upgrade-info-v3.json", upgradetypes.Plan{Name: "chain3", Height: 200})
+	// non-matching and invalid files are ignored
+	write("upgrade-info.json", upgradetypes.Plan{Name: "chain-other", Height: 50})
+	write("plan-v1.json", upgradetypes.Plan{Name: "chain-other", Height: 60})
+	require.NoError(os.WriteFile(filepath.Join(dir, "upgrade-info-broken.json"), []byte("{"), 0o600))
+
+	chain1 := upgradetypes.Plan{Name: "chain1", Height: 10}
+	height = 50
+	require.False(fw.CheckUpdate(chain1))
+	require.Equal(v2, fw.filename)
+
+	height = 100
+	require.True(fw.CheckUpdate(chain1))
+	require.Equal("chain2", fw.currentInfo.Name)
+
+	// once applied, the next plan by height is watched
+	fw.needsUpdate = false
+	chain2 := upgradetypes.Plan{Name: "chain2", Height: 100}
+	require.False(fw.CheckUpdate(chain2))
+	require.Equal(v3, fw.filename)
+
+	// a plan added in between is picked up on the next poll
+	v25 := write("upgrade-info-v2.5.json", upgradetypes.Plan{Name: "chain2.5", Height: 150})
+	height = 150
+	require.True(fw.CheckUpdate(chain2))
+	require.Equal(v25, fw.filename)
+	require.Equal("chain2.5", fw.currentInfo.Name)
+
+	// removed plans are no longer applicable
+	fw.needsUpdate = false
+	require.NoError(os.Remove(v3))
+	require.False(fw.CheckUpdate(upgradetypes.Plan{Name: "chain2.5", Height: 150}))
+	// the file of the applied upgrade is still the one the upgrade reads
+	require.Equal(v25, fw.filename)
+}
+
+func TestCheckUpdateUpgradeInfoGlobInvalid(t *testing.T) {
+	require := require.New(t)
+
+	srv := newCallbackRecorder(t)
+	quarantineDir := filepath.Join(t.TempDir(), "quarantine")
+	cfg := &Config{
+		Home:            t.TempDir(),
+		Name:            "dummyd",
+		CallbackAPI:     srv.URL,
+		UpgradeInfoGlob: "upgrade-info-*.json",
+		QuarantineDir:   quarantineDir,
+		QuarantineAfter: 2,
+	}
+	fw := newTestWatcher(t, cfg)
+	var height int64 = 50
+	fw.getHeight = func() (int64, error) { return height, nil }
+
+	dir := filepath.Dir(cfg.UpgradeInfoFilePath())
+	bz, err := json.Marshal(upgradetypes.Plan{Name: "chain2", Height: 100})
+	require.NoError(err)
+	valid := filepath.Join(dir, "upgrade-info-v2.json")
+	require.NoError(os.WriteFile(valid, bz, 0o600))
+	broken := filepath.Join(dir, "upgrade-info-broken.json")
+	require.NoError(os.WriteFile(broken, []byte("{"), 0o600))
+	invalid := func() []callbackInfo {
+		return srv.received("/internal/cosmos///" + callbackPaths[CallbackEventValidationFailed])
+	}
+	quarantined := func() []callbackInfo {
+		return srv.received("/internal/cosmos///" + callbackPaths[CallbackEventQuarantined])
+	}
+
+	// the invalid file is reported, while the valid one is watched
+	require.False(fw.CheckUpdate(upgradetypes.Plan{}))
+	require.Equal(valid, fw.filename)
+	require.Len(invalid(), 1)
+	require.Contains(invalid()[0].Error, broken)
+	require.Empty(quarantined())
+
+	// it is reported once, then quarantined as it stays invalid
+	require.False(fw.CheckUpdate(upgradetypes.Plan{}))
+	require.Len(invalid(), 1)
+	require.Len(quarantined(), 1)
+	require.NoFileExists(broken)
+	entries, err := os.ReadDir(quarantineDir)
+	require.NoError(err)
+	require.Len(entries, 1)
+
+	height = 100
+	require.True(fw.CheckUpdate(upgradetypes.Plan{}))
+	require.Equal(valid, fw.filename)
+	require.Equal("chain2", fw.currentInfo.Name)
+	require.Len(invalid(), 1)
+}
+
+func TestCheckUpdateHeightGracePeriod(t *testing.T) {
+	cases := map[string]struct {
+		policy       string
+		elapsed      time.Duration
+		expectUpdate bool
+	}{
+		"within grace, open":   {policy: HeightFailureOpen, elapsed: 30 * time.Second, expectUpdate: false},
+		"within grace, closed": {policy: HeightFailureClosed, elapsed: 30 * time.Second, expectUpdate: false},
+		"past grace, open":     {policy: HeightFailureOpen, elapsed: 2 * time.Minute, expectUpdate: true},
+		"past grace, closed":   {policy: HeightFailureClosed, elapsed: 2 * time.Minute, expectUpdate: false},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			cfg := &Config{Home: t.TempDir(), Name: "dummyd", HeightGracePeriod: time.Minute, HeightFailurePolicy: tc.policy}
+			fw := newTestWatcher(t, cfg)
+			fw.getHeight = func() (int64, error) { return 0, errors.New("status failed") }
+			now := fw.startedAt.Add(tc.elapsed)
+			fw.now = func() time.Time { return now }
+
+			writeUpgradeInfo(t, cfg, upgradetypes.Plan{Name: "chain2", Height: 100})
+			require.Equal(t, tc.expectUpdate, fw.CheckUpdate(upgradetypes.Plan{Name: "chain1"}))
+		})
+	}
+}
+
+func TestCheckUpdateHeightFailureClosed(t *testing.T) {
+	cfg := &Config{Home: t.TempDir(), Name: "dummyd", HeightGracePeriod: time.Minute, HeightFailurePolicy: HeightFailureClosed}
+	fw := newTestWatcher(t, cfg)
+	fw.now = func() time.Time { return fw.startedAt }
+	var height int64 = 50
+	var heightErr error
+	fw.getHeight = func() (int64, error) { return height, heightErr }
+
+	writeUpgradeInfo(t, cfg, upgradetypes.Plan{Name: "chain2", Height: 100})
+	require.False(t, fw.CheckUpdate(upgradetypes.Plan{Name: "chain1"}))
+
+	// once a height was read, losing it is not a startup delay and holds the upgrade right away
+	height, heightErr = 0, errors.New("status failed")
+	require.False(t, fw.CheckUpdate(upgradetypes.Plan{Name: "chain1"}))
+
+	// the upgrade proceeds as soon as the height can be read again
+	height, heightErr = 100, nil
+	require.True(t, fw.CheckUpdate(upgradetypes.Plan{Name: "chain1"}))
+}
+
+func TestCheckUpdateHeightUnknownAfterExit(t *testing.T) {
+	cases := map[string]struct {
+		lastHeight   int64
+		expectUpdate bool
+	}{
+		"halted at the upgrade height": {lastHeight: 99, expectUpdate: true},
+		"past the upgrade height":      {lastHeight: 101, expectUpdate: true},
+		"crashed long before":          {lastHeight: 50},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			cfg := &Config{Home: t.TempDir(), Name: "dummyd", HeightFailurePolicy: HeightFailureClosed}
+			fw := newTestWatcher(t, cfg)
+			fw.getHeight = func() (int64, error) { return 0, errors.New("status failed") }
+			fw.lastHeight = tc.lastHeight
+
+			// the status command fails once the app exited, the last known height is used
+			writeUpgradeInfo(t, cfg, upgradetypes.Plan{Name: "chain2", Height: 100})
+			fw.sleep = func(time.Duration) { require.Fail(t, "the upgrade is not held") }
+			require.Equal(t, tc.expectUpdate, fw.checkAfterExit(upgradetypes.Plan{Name: "chain1"}))
+		})
+	}
+}
+
+func TestCheckUpdateHeightUnknownNearUpgrade(t *testing.T) {
+	cfg := &Config{Home: t.TempDir(), Name: "dummyd", HeightFailurePolicy: HeightFailureClosed, HeightTolerance: 2}
+	fw := newTestWatcher(t, cfg)
+	fw.getHeight = func() (int64, error) { return 0, errors.New("status failed") }
+	writeUpgradeInfo(t, cfg, upgradetypes.Plan{Name: "chain2", Height: 100})
+
+	// far from the upgrade height the upgrade is held while the app runs
+	fw.lastHeight = 90
+	require.False(t, fw.CheckUpdate(upgradetypes.Plan{Name: "chain1"}))
+
+	// within the tolerance of it the last known height is used, here past the upgrade height
+	fw.lastHeight = 101
+	require.True(t, fw.CheckUpdate(upgradetypes.Plan{Name: "chain1"}))
+}
+
+func TestCheckUpdateRestartHeuristicDelay(t *testing.T) {
+	cases := map[string]struct {
+		delay        time.Duration
+		height       int64 // read after the delay
+		written      *upgradetypes.Plan
+		expectUpdate bool
+		expectSleep  bool
+	}{
+		"no delay":                   {expectUpdate: true},
+		"still differs":              {delay: time.Second, expectUpdate: true, expectSleep: true},
+		"height not reached":         {delay: time.Second, height: 40, expectSleep: true},
+		"height reached":             {delay: time.Second, height: 49, expectUpdate: true, expectSleep: true},
+		"current upgrade written":    {delay: time.Second, written: &upgradetypes.Plan{Name: "chain2", Height: 49}, expectSleep: true},
+		"previous upgrade confirmed": {delay: time.Second, written: &upgradetypes.Plan{Name: "chain1", Height: 20}, expectUpdate: true, expectSleep: true},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			cfg := &Config{Home: t.TempDir(), Name: "dummyd", RestartHeuristicDelay: tc.delay}
+			fw := newTestWatcher(t, cfg)
+			// the node reports no height while it starts
+			var height int64
+			fw.getHeight = func() (int64, error) { return height, nil }
+			var slept time.Duration
+			fw.sleep = func(d time.Duration) {
+				slept = d
+				height = tc.height
+				if tc.written != nil {
+					writeCurrentUpgrade(t, cfg, *tc.written)
+				}
+			}
+
+			writeUpgradeInfo(t, cfg, upgradetypes.Plan{Name: "chain2", Height: 49})
+			require.Equal(t, tc.expectUpdate, fw.CheckUpdate(upgradetypes.Plan{}))
+			if tc.expectSleep {
+				require.Equal(t, tc.delay, slept)
+			} else {
+				require.Zero(t, slept)
+			}
+		})
+	}
+}
+
+func TestCheckUpdateRestartHeuristicDelayRetried(t *testing.T) {
+	require := require.New(t)
+	cfg := &Config{Home: t.TempDir(), Name: "dummyd", RestartHeuristicDelay: time.Second}
+	fw := newTestWatcher(t, cfg)
+	var height int64
+	fw.getHeight = func() (int64, error) { return height, nil }
+	fw.sleep = func(time.Duration) {
+		if height == 0 {
+			height = 40
+		}
+	}
+
+	// a transient startup state does not upgrade, nor does it mark the plan handled
+	writeUpgradeInfo(t, cfg, upgradetypes.Plan{Name: "chain2", Height: 49})
+	require.False(fw.CheckUpdate(upgradetypes.Plan{}))
+	require.False(fw.initialized)
+
+	height = 49
+	require.True(fw.CheckUpdate(upgradetypes.Plan{}))
+}
+
+func TestResolveCurrentBinRetries(t *testing.T) {
+	cases := map[string]struct {
+		retries      int
+		appearsAfter int // sleeps before the binary appears, present from the start if negative, never if 0
+		expectErr    bool
+		expectSleeps int
+	}{
+		"binary present":        {retries: 3, appearsAfter: -1, expectSleeps: 0},
+		"appears after retries": {retries: 3, appearsAfter: 2, expectSleeps: 2},
+		"retries exhausted":     {retries: 2, expectErr: true, expectSleeps: 2},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			cfg := &Config{Home: t.TempDir(), Name: "dummyd", BinResolveRetries: tc.retries, BinResolveDelay: time.Minute}
+			require.NoError(t, os.MkdirAll(cfg.Root(), 0o755))
+			if tc.appearsAfter < 0 {
+				writeStatusBin(t, cfg.GenesisBin(), 0)
+			}
+
+			sleeps := 0
+			bin, err := resolveCurrentBin(cfg, log.NewNopLogger(), func(d time.Duration) {
+				require.Equal(t, time.Minute, d)
+				sleeps++
+				if sleeps == tc.appearsAfter {
+					writeStatusBin(t, cfg.GenesisBin(), 0)
+				}
+			})
+			require.Equal(t, tc.expectSleeps, sleeps)
+			if tc.expectErr {
+				require.ErrorContains(t, err, "error creating symlink to genesis: current binary not found")
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, cfg.GenesisBin(), bin)
+		})
+	}
+}
+
+func TestResolveCurrentBinNoRetries(t *testing.T) {
+	// the cosmovisor directory is not mounted yet
+	cfg := &Config{Home: t.TempDir(), Name: "dummyd"}
+	_, err := resolveCurrentBin(cfg, log.NewNopLogger(), func(time.Duration) {
+		require.Fail(t, "retried without retries")
+	})
+	require.ErrorContains(t, err, "error creating symlink to genesis")
+}
@@ -0,0 +1,61 @@
+package cosmovisor
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/santhosh-tekuri/jsonschema/v5"
+)
+
+// loadUpgradeInfoSchema compiles the JSON schema the upgrade-info.json file is validated against.
+// It returns nil if no schema path is set.
+func loadUpgradeInfoSchema(path string) (*jsonschema.Schema, error) {
+	if path == "" {
+		return nil, nil
+	}
+
+	schema, err := jsonschema.Compile(path)
+	if err != nil {
+		return nil, fmt.Errorf("invalid upgrade-info.json schema %s: %w", path, err)
+	}
+
+	return schema, nil
+}
+
+// validateUpgradeInfoSchema validates the upgrade-info.json content against the schema.
+func validateUpgradeInfoSchema(schema *jsonschema.Schema, bz []byte) error {
+	var doc interface{}
+	dec := json.NewDecoder(bytes.NewReader(bz))
+	dec.UseNumber()
+	if err := dec.Decode(&doc); err != nil {
+		return err
+	}
+
+	err := schema.Validate(doc)
+	var verr *jsonschema.ValidationError
+	if !errors.As(err, &verr) {
+		return err
+	}
+
+	return fmt.Errorf("%w: %s", ErrUpgradeInfoSchema, strings.Join(schemaViolations(verr), "; "))
+}
+
+// schemaViolations flattens a validation error into one "field: message" entry per violation.
+func schemaViolations(verr *jsonschema.ValidationError) []string {
+	if len(verr.Causes) == 0 {
+		field := strings.ReplaceAll(strings.TrimPrefix(verr.InstanceLocation, "/"), "/", ".")
+		if field == "" {
+			field = "(root)"
+		}
+		return []string{field + ": " + verr.Message}
+	}
+
+	var violations []string
+	for _, cause := range verr.Causes {
+		violations = append(violations, schemaViolations(cause)...)
+	}
+	return violations
+}
@@ -0,0 +1,44 @@
+package cosmovisor
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// loadSecret loads the secret referenced by ref, which is either a file path, a file:// URI or
+// an env://NAME URI reading the NAME env var. A missing or empty secret is an error.
+func loadSecret(ref string) (string, error) {
+	var secret string
+	switch {
+	case strings.HasPrefix(ref, "env://"):
+		name := strings.TrimPrefix(ref, "env://")
+		if name == "" {
+			return "", errors.New("missing env var name")
+		}
+		val, ok := os.LookupEnv(name)
+		if !ok {
+			return "", fmt.Errorf("env var %s is not set", name)
+		}
+		secret = val
+
+	case strings.HasPrefix(ref, "file://"), !strings.Contains(ref, "://"):
+		path := strings.TrimPrefix(ref, "file://")
+		bz, err := os.ReadFile(path)
+		if err != nil {
+			return "", fmt.Errorf("failed to read secret file: %w", err)
+		}
+		secret = string(bz)
+
+	default:
+		return "", fmt.Errorf("unsupported secret reference %q, expected a file path, file:// or env://", ref)
+	}
+
+	secret = strings.TrimSpace(secret)
+	if secret == "" {
+		return "", fmt.Errorf("secret %s is empty", ref)
+	}
+
+	return secret, nil
+}
@@ -0,0 +1,47 @@
+package cosmovisor
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadSecret(t *testing.T) {
+	dir := t.TempDir()
+	secretFile := filepath.Join(dir, "callback-secret")
+	require.NoError(t, os.WriteFile(secretFile, []byte("s3cr3t\n"), 0o600))
+	emptyFile := filepath.Join(dir, "empty")
+	require.NoError(t, os.WriteFile(emptyFile, nil, 0o600))
+	t.Setenv("COSMOVISOR_TEST_SECRET", " s3cr3t ")
+	t.Setenv("COSMOVISOR_TEST_EMPTY_SECRET", "")
+
+	cases := map[string]struct {
+		ref       string
+		expectErr string
+	}{
+		"file path":            {ref: secretFile},
+		"file uri":             {ref: "file://" + secretFile},
+		"env uri":              {ref: "env://COSMOVISOR_TEST_SECRET"},
+		"missing file":         {ref: filepath.Join(dir, "missing"), expectErr: "failed to read secret file"},
+		"empty file":           {ref: emptyFile, expectErr: "is empty"},
+		"missing env var":      {ref: "env://COSMOVISOR_TEST_MISSING_SECRET", expectErr: "is not set"},
+		"empty env var":        {ref: "env://COSMOVISOR_TEST_EMPTY_SECRET", expectErr: "is empty"},
+		"unsupported":          {ref: "vault://callbacks/secret", expectErr: "unsupported secret reference"},
+		"env uri without name": {ref: "env://", expectErr: "missing env var name"},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			secret, err := loadSecret(tc.ref)
+			if tc.expectErr != "" {
+				require.ErrorContains(t, err, tc.expectErr)
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, "s3cr3t", secret)
+		})
+	}
+}
@@ -0,0 +1,228 @@
+package cosmovisor
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"cosmossdk.io/log"
+	upgradetypes "cosmossdk.io/x/upgrade/types"
+)
+
+// Self test stages, in the order they run.
+const (
+	SelfTestStageParse      = "parse"
+	SelfTestStageHeightGate = "height gate"
+	SelfTestStageCallback   = "callback"
+)
+
+// selfTestPlan is the synthetic upgrade plan the self test detects.
+var selfTestPlan = upgradetypes.Plan{Name: "cosmovisor-selftest", Height: 100}
+
+// selfTestTag is the callback tag marking the callbacks sent by the self test, telling them
+// apart from real upgrades on the callback API.
+const selfTestTag = "selftest"
+
+// SelfTestResult is the outcome of a self test stage, Err is nil if it passed.
+type SelfTestResult struct {
+	Stage string
+	Err   error
+}
+
+// SelfTest exercises the upgrade detection end to end in a temporary directory: it writes a
+// synthetic upgrade-info.json, checks it with a simulated block height below and then at the
+// upgrade height, and verifies the resulting callbacks are delivered. Callbacks go to the
+// configured callback API, or to a local stub if none is configured or in dry-run mode, tagged
+// with selfTestTag.
+// An error is returned when the self test could not be set up.
+func SelfTest(cfg *Config, logger log.Logger) ([]SelfTestResult, error) {
+	home, err := os.MkdirTemp("", "cosmovisor-selftest-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(home)
+
+	// only the callback settings of the deployment are kept, anything else could hold the upgrade
+	test := &Config{
+		Home:                     home,
+		Name:                     "selftestd",
+		PollInterval:             time.Second, // the watcher is checked directly, never polled
+		DisableRecase:            cfg.DisableRecase,
+		RecaseExceptions:         cfg.RecaseExceptions,
+		ExpandInfoEnv:            cfg.ExpandInfoEnv,
+		CallbackAPI:              cfg.CallbackAPI,
+		NodeID:                   cfg.NodeID,
+		DeploymentID:             cfg.DeploymentID,
+		EventEndpoints:           cfg.EventEndpoints,
+		CallbackTags:             map[string]string{selfTestTag: "true"},
+		HTTPProxy:                cfg.HTTPProxy,
+		NoProxy:                  cfg.NoProxy,
+		CallbackBreakerThreshold: cfg.CallbackBreakerThreshold,
+		CallbackBreakerCooldown:  cfg.CallbackBreakerCooldown,
+		CallbackAuth:             cfg.CallbackAuth,
+		callbackSecret:           cfg.callbackSecret,
+	}
+	for key, value := range cfg.CallbackTags {
+		if key != selfTestTag {
+			test.CallbackTags[key] = value
+		}
+	}
+	if test.CallbackAPI == "" && len(test.EventEndpoints) == 0 || cfg.CallbackDryRun {
+		stub, err := newCallbackStub()
+		if err != nil {
+			return nil, fmt.Errorf("failed to start the callback stub: %w", err)
+		}
+		defer stub.Close()
+
+		test.CallbackAPI = stub.url
+		test.EventEndpoints = nil
+		test.CallbackAuth = nil
+		test.callbackSecret = ""
+	}
+
+	if err := os.MkdirAll(filepath.Dir(test.UpgradeInfoFilePath()), 0o755); err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(filepath.Dir(test.GenesisBin()), 0o755); err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(test.GenesisBin(), []byte("#!/bin/sh\n"), 0o755); err != nil { //nolint:gosec // the genesis binary must be executable
+		return nil, err
+	}
+
+	fw, err := newUpgradeFileWatcher(test, logger)
+	if err != nil {
+		return nil, err
+	}
+	defer fw.Stop()
+
+	delivered := &deliveryRecorder{RoundTripper: fw.callbacks.client.Transport}
+	if delivered.RoundTripper == nil {
+		delivered.RoundTripper = http.DefaultTransport
+	}
+	fw.callbacks.client = &http.Client{Transport: delivered}
+
+	results := make([]SelfTestResult, 0, 3)
+	fail := func(stage string, err error) []SelfTestResult {
+		results = append(results, SelfTestResult{Stage: stage, Err: err})
+		for _, skipped := range []string{SelfTestStageParse, SelfTestStageHeightGate, SelfTestStageCallback}[len(results):] {
+			results = append(results, SelfTestResult{Stage: skipped, Err: fmt.Errorf("skipped, the %s stage failed", stage)})
+		}
+		return results
+	}
+
+	// parse
+	if err := writeSelfTestPlan(test.UpgradeInfoFilePath()); err != nil {
+		return nil, err
+	}
+	plan, err := ParseUpgradeInfoFile(test.UpgradeInfoFilePath(), fw.parseOpts...)
+	if err == nil && (plan.Name != selfTestPlan.Name || plan.Height != selfTestPlan.Height) {
+		err = fmt.Errorf("parsed plan %s at height %d, expected %s at height %d", plan.Name, plan.Height, selfTestPlan.Name, selfTestPlan.Height)
+	}
+	if err != nil {
+		return fail(SelfTestStageParse, err), nil
+	}
+	results = append(results, SelfTestResult{Stage: SelfTestStageParse})
+
+	// height gate
+	height := selfTestPlan.Height - 1
+	fw.getHeight = func() (int64, error) { return height, nil }
+	if fw.CheckUpdate(upgradetypes.Plan{}) {
+		return fail(SelfTestStageHeightGate, fmt.Errorf("upgrade triggered at height %d, before the upgrade height", height)), nil
+	}
+	height = selfTestPlan.Height
+	if !fw.CheckUpdate(upgradetypes.Plan{}) {
+		return fail(SelfTestStageHeightGate, fmt.Errorf("upgrade not triggered at the upgrade height %d", height)), nil
+	}
+	results = append(results, SelfTestResult{Stage: SelfTestStageHeightGate})
+
+	// callback
+	var errs []error
+	for _, event := range []CallbackEvent{CallbackEventDetected, CallbackEventReached} {
+		if err := delivered.check(fw.callbacks.endpoint(event)); err != nil {
+			errs = append(errs, fmt.Errorf("%s callback: %w", event, err))
+		}
+	}
+
+	return append(results, SelfTestResult{Stage: SelfTestStageCallback, Err: errors.Join(errs...)}), nil
+}
+
+func writeSelfTestPlan(path string) error {
+	bz, err := json.Marshal(selfTestPlan)
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(path, bz, 0o600)
+}
+
+// deliveryRecorder records the outcome of the callbacks sent through it, by URL.
+type deliveryRecorder struct {
+	http.RoundTripper
+
+	mu      sync.Mutex
+	results map[string]error
+}
+
+func (r *deliveryRecorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := r.RoundTripper.RoundTrip(req)
+
+	result := err
+	if err == nil && (resp.StatusCode < 200 || resp.StatusCode >= 300) {
+		result = fmt.Errorf("returned status %s", resp.Status)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.results == nil {
+		r.results = make(map[string]error)
+	}
+	// a single successful delivery is enough
+	if prev, ok := r.results[req.URL.String()]; !ok || prev != nil {
+		r.results[req.URL.String()] = result
+	}
+
+	return resp, err
+}
+
+// check returns the error of the callbacks sent to url, if none was delivered.
+func (r *deliveryRecorder) check(url string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	err, ok := r.results[url]
+	if !ok {
+		return fmt.Errorf("not sent to %s", url)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to reach %s: %w", url, err)
+	}
+
+	return nil
+}
+
+// callbackStub is a local callback API accepting every callback.
+type callbackStub struct {
+	*http.Server
+	url string
+}
+
+func newCallbackStub() (*callbackStub, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+
+	srv := &http.Server{ //nolint:gosec // the stub is only reachable locally for the duration of the self test
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {}),
+	}
+	go func() { _ = srv.Serve(listener) }()
+
+	return &callbackStub{Server: srv, url: "http://" + listener.Addr().String()}, nil
+}
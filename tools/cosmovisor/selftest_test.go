@@ -0,0 +1,67 @@
+package cosmovisor
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"cosmossdk.io/log"
+)
+
+func TestSelfTest(t *testing.T) {
+	tmp := t.TempDir()
+	t.Setenv("TMPDIR", tmp)
+
+	expectPassed := []SelfTestResult{{Stage: SelfTestStageParse}, {Stage: SelfTestStageHeightGate}, {Stage: SelfTestStageCallback}}
+
+	// without a callback API the callbacks go to a local stub
+	results, err := SelfTest(&Config{}, log.NewNopLogger())
+	require.NoError(t, err)
+	require.Equal(t, expectPassed, results)
+
+	// a configured callback API receives the synthetic upgrade
+	srv := newCallbackRecorder(t)
+	tags := map[string]string{"env": "prod", selfTestTag: "false"}
+	results, err = SelfTest(&Config{CallbackAPI: srv.URL, NodeID: "node", DeploymentID: "deployment", CallbackTags: tags}, log.NewNopLogger())
+	require.NoError(t, err)
+	require.Equal(t, expectPassed, results)
+	got := srv.received("/internal/cosmos/node/deployment/" + callbackPaths[CallbackEventReached])
+	require.Len(t, got, 1)
+	require.Equal(t, "cosmovisor-selftest", got[0].Name)
+	require.Equal(t, int64(100), got[0].Height)
+
+	// tagged so the callback API can tell it apart from a real upgrade
+	require.Equal(t, map[string]string{"env": "prod", selfTestTag: "true"}, got[0].Tags)
+	require.Equal(t, map[string]string{"env": "prod", selfTestTag: "false"}, tags)
+
+	// but not in dry-run mode
+	results, err = SelfTest(&Config{CallbackAPI: srv.URL, NodeID: "node", DeploymentID: "deployment", CallbackDryRun: true}, log.NewNopLogger())
+	require.NoError(t, err)
+	require.Equal(t, expectPassed, results)
+	require.Len(t, srv.received("/internal/cosmos/node/deployment/"+callbackPaths[CallbackEventReached]), 1)
+
+	// the temporary files are cleaned up
+	entries, err := os.ReadDir(tmp)
+	require.NoError(t, err)
+	require.Empty(t, entries)
+}
+
+func TestSelfTestCallbackFailure(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer srv.Close()
+
+	results, err := SelfTest(&Config{CallbackAPI: srv.URL}, log.NewNopLogger())
+	require.NoError(t, err)
+	require.Len(t, results, 3)
+	require.NoError(t, results[0].Err)
+	require.NoError(t, results[1].Err)
+	require.Equal(t, SelfTestStageCallback, results[2].Stage)
+	require.ErrorContains(t, results[2].Err, "detected callback: failed to reach")
+	require.ErrorContains(t, results[2].Err, "reached callback: failed to reach")
+	require.ErrorContains(t, results[2].Err, "401 Unauthorized")
+}
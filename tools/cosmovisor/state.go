@@ -0,0 +1,90 @@
+package cosmovisor
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// stateFilename is the file the watcher persists its state in across restarts.
+const stateFilename = "cosmovisor-state.json"
+
+// watcherState is the state of the upgrade watcher persisted across restarts.
+type watcherState struct {
+	// LastSignal is the last upgrade the watcher signaled.
+	LastSignal *upgradeSignal `json:"last_signal,omitempty"`
+	// LastSignalTime is when an upgrade was last signaled.
+	LastSignalTime time.Time `json:"last_signal_time"`
+	// Milestones are the height milestones fired for the last plan counted down to.
+	Milestones *firedMilestones `json:"milestones,omitempty"`
+}
+
+// upgradeSignal counts how many times an upgrade was signaled.
+type upgradeSignal struct {
+	Name   string `json:"name"`
+	Height int64  `json:"height"`
+	Count  int    `json:"count"`
+}
+
+// firedMilestones are the height milestones fired for an upgrade plan.
+type firedMilestones struct {
+	Name   string  `json:"name"`
+	Height int64   `json:"height"`
+	Fired  []int64 `json:"fired"`
+}
+
+// fired reports whether the milestone at offset was fired.
+func (m *firedMilestones) fired(offset int64) bool {
+	for _, fired := range m.Fired {
+		if fired == offset {
+			return true
+		}
+	}
+	return false
+}
+
+// loadWatcherState reads the state file. A missing file is an empty state.
+func loadWatcherState(path string) (watcherState, error) {
+	var state watcherState
+	bz, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return state, nil
+		}
+		return state, err
+	}
+
+	if err := json.Unmarshal(bz, &state); err != nil {
+		return watcherState{}, fmt.Errorf("invalid state file %s: %w", path, err)
+	}
+
+	return state, nil
+}
+
+// saveWatcherState atomically replaces the state file.
+func saveWatcherState(path string, state watcherState) error {
+	bz, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), stateFilename+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(bz); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
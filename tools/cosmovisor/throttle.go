@@ -0,0 +1,112 @@
+package cosmovisor
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"cosmossdk.io/log"
+)
+
+// defaultLogThrottleInterval is used when Config.LogThrottleInterval is not set.
+const defaultLogThrottleInterval = time.Minute
+
+// throttledLogger is a logger which logs a repeating identical error at most once per interval.
+// When an error is logged again after the interval, the number of suppressed occurrences is
+// added to it. The loggers returned by With are throttled as well, sharing the seen errors.
+type throttledLogger struct {
+	log.Logger
+
+	interval time.Duration
+	now      func() time.Time
+
+	// context are the key/value pairs added by With, part of what makes two errors identical.
+	context []any
+	state   *throttleState
+}
+
+// throttleState holds the errors seen by a throttledLogger and the loggers derived from it.
+type throttleState struct {
+	mu   sync.Mutex
+	seen map[string]*throttledEntry
+
+	// pruned is when the entries were last pruned.
+	pruned time.Time
+}
+
+type throttledEntry struct {
+	last       time.Time
+	suppressed int
+}
+
+func newThrottledLogger(logger log.Logger, interval time.Duration) *throttledLogger {
+	if interval <= 0 {
+		interval = defaultLogThrottleInterval
+	}
+
+	return &throttledLogger{
+		Logger:   logger,
+		interval: interval,
+		now:      time.Now,
+		state:    &throttleState{seen: make(map[string]*throttledEntry)},
+	}
+}
+
+// With returns a throttled logger with additional context.
+func (l *throttledLogger) With(keyVals ...any) log.Logger {
+	context := make([]any, 0, len(l.context)+len(keyVals))
+	context = append(append(context, l.context...), keyVals...)
+
+	return &throttledLogger{
+		Logger:   l.Logger.With(keyVals...),
+		interval: l.interval,
+		now:      l.now,
+		context:  context,
+		state:    l.state,
+	}
+}
+
+// Error logs the error unless an identical one was logged less than the interval ago.
+func (l *throttledLogger) Error(msg string, keyVals ...any) {
+	key := fmt.Sprint(l.context, msg, keyVals)
+	now := l.now()
+
+	s := l.state
+	s.mu.Lock()
+	l.prune(now)
+	entry, ok := s.seen[key]
+	if ok && now.Sub(entry.last) < l.interval {
+		entry.suppressed++
+		s.mu.Unlock()
+		return
+	}
+
+	suppressed := 0
+	if ok {
+		suppressed = entry.suppressed
+	}
+	s.seen[key] = &throttledEntry{last: now}
+	s.mu.Unlock()
+
+	if suppressed > 0 {
+		keyVals = append(keyVals, "suppressed", suppressed)
+	}
+	l.Logger.Error(msg, keyVals...)
+}
+
+// prune drops, at most once per interval, the errors last logged more than two intervals ago,
+// along with their suppressed count, so errors which stopped repeating are not kept forever.
+// It must be called with the state locked.
+func (l *throttledLogger) prune(now time.Time) {
+	s := l.state
+	if now.Sub(s.pruned) < l.interval {
+		return
+	}
+	s.pruned = now
+
+	for key, entry := range s.seen {
+		if now.Sub(entry.last) >= 2*l.interval {
+			delete(s.seen, key)
+		}
+	}
+}
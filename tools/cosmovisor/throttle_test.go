@@ -0,0 +1,136 @@
+package cosmovisor
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"cosmossdk.io/log"
+)
+
+func TestThrottledLogger(t *testing.T) {
+	require := require.New(t)
+
+	var buf bytes.Buffer
+	now := time.Unix(0, 0)
+	l := newThrottledLogger(log.NewLogger(&buf, log.ColorOption(false)), time.Minute)
+	l.now = func() time.Time { return now }
+
+	lines := func() []string {
+		return strings.Split(strings.TrimSpace(buf.String()), "\n")
+	}
+
+	// identical errors within the interval are suppressed
+	for i := 0; i < 5; i++ {
+		l.Error("failed to check current height", "error", "exit status 1")
+		now = now.Add(time.Second)
+	}
+	require.Len(lines(), 1)
+	require.NotContains(buf.String(), "suppressed")
+
+	// a different error is not affected
+	l.Error("failed to check current height", "error", "signal: killed")
+	require.Len(lines(), 2)
+
+	// after the interval the error is logged again with the suppressed count
+	now = now.Add(time.Minute)
+	l.Error("failed to check current height", "error", "exit status 1")
+	require.Len(lines(), 3)
+	require.Contains(lines()[2], "suppressed=4")
+
+	// the count is reset after re-emission
+	now = now.Add(time.Minute)
+	l.Error("failed to check current height", "error", "exit status 1")
+	require.Len(lines(), 4)
+	require.NotContains(lines()[3], "suppressed")
+
+	// other levels are never throttled
+	l.Info("sending upgrade callback")
+	l.Info("sending upgrade callback")
+	require.Len(lines(), 6)
+}
+
+func TestThrottledLoggerWith(t *testing.T) {
+	require := require.New(t)
+
+	var buf bytes.Buffer
+	now := time.Unix(0, 0)
+	l := newThrottledLogger(log.NewLogger(&buf, log.ColorOption(false)), time.Minute)
+	l.now = func() time.Time { return now }
+
+	lines := func() []string {
+		return strings.Split(strings.TrimSpace(buf.String()), "\n")
+	}
+
+	// the derived loggers are throttled too
+	derived := l.With("module", "watcher")
+	derived.Error("failed to check current height")
+	derived.Error("failed to check current height")
+	l.With("module", "watcher").Error("failed to check current height")
+	require.Len(lines(), 1)
+	require.Contains(lines()[0], "module=watcher")
+
+	// but their context makes for different errors
+	l.Error("failed to check current height")
+	l.With("module", "callback").Error("failed to check current height")
+	require.Len(lines(), 3)
+
+	now = now.Add(time.Minute)
+	derived.Error("failed to check current height")
+	require.Len(lines(), 4)
+	require.Contains(lines()[3], "suppressed=2")
+}
+
+func TestThrottledLoggerPrune(t *testing.T) {
+	require := require.New(t)
+
+	now := time.Unix(0, 0)
+	l := newThrottledLogger(log.NewNopLogger(), time.Minute)
+	l.now = func() time.Time { return now }
+
+	for i := 0; i < 100; i++ {
+		l.Error("failed to check current height", "height", i)
+	}
+	require.Len(l.state.seen, 100)
+
+	// errors repeating within the interval are kept
+	now = now.Add(90 * time.Second)
+	l.Error("failed to check current height", "height", 0)
+	require.Len(l.state.seen, 100)
+
+	// the others are dropped once they were last logged two intervals ago, at the next pruning
+	now = now.Add(30 * time.Second)
+	l.Error("failed to check current height", "height", 100)
+	require.Len(l.state.seen, 101)
+	now = now.Add(30 * time.Second)
+	l.Error("failed to check current height", "height", 100)
+	require.Len(l.state.seen, 2)
+}
+
+func TestWatcherThrottlesCallbackErrors(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	cfg := &Config{Home: t.TempDir(), Name: "dummyd", PollInterval: time.Millisecond, CallbackAPI: srv.URL}
+	require.NoError(t, os.MkdirAll(filepath.Dir(cfg.UpgradeInfoFilePath()), 0o755))
+	writeStatusBin(t, cfg.GenesisBin(), 0)
+
+	var buf bytes.Buffer
+	fw, err := newUpgradeFileWatcher(cfg, log.NewLogger(&buf, log.ColorOption(false)))
+	require.NoError(t, err)
+
+	// the callbacks log through the watcher throttled logger
+	for i := 0; i < 3; i++ {
+		require.Error(t, fw.callbacks.send(CallbackEventDetected, callbackInfo{Name: "chain2"}))
+	}
+	require.Equal(t, 1, strings.Count(buf.String(), "upgrade callback failed"))
+}
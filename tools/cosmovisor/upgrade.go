@@ -3,8 +3,10 @@ package cosmovisor
 import (
 	"errors"
 	"fmt"
+	neturl "net/url"
 	"os"
 	"runtime"
+	"strings"
 
 	"cosmossdk.io/log"
 	"cosmossdk.io/x/upgrade/plan"
@@ -48,7 +50,7 @@ func UpgradeBinary(logger log.Logger, cfg *Config, p upgradetypes.Plan) error {
 		return fmt.Errorf("invalid binaries: %w", err)
 	}
 
-	url, err := GetBinaryURL(upgradeInfo.Binaries)
+	url, err := GetPreferredBinaryURL(upgradeInfo.Binaries, cfg.PlatformPreference)
 	if err != nil {
 		return err
 	}
@@ -68,16 +70,108 @@ func UpgradeBinary(logger log.Logger, cfg *Config, p upgradetypes.Plan) error {
 	return cfg.SetCurrentUpgrade(p)
 }
 
+// BinaryRef is a binary listed in the plan info, with the checksum split from its URL.
+type BinaryRef struct {
+	// URL is the download URL without the checksum query parameter.
+	URL string
+	// Checksum is the checksum query parameter of the URL (e.g. "sha256:..."), if any.
+	Checksum string
+}
+
+// ParseUpgradeBinaries parses the plan info and returns its binaries keyed by os/arch (or "any").
+func ParseUpgradeBinaries(info string) (map[string]BinaryRef, error) {
+	upgradeInfo, err := plan.ParseInfo(info)
+	if err != nil {
+		return nil, err
+	}
+
+	binaries := make(map[string]BinaryRef, len(upgradeInfo.Binaries))
+	for platform, rawURL := range upgradeInfo.Binaries {
+		ref, err := parseBinaryRef(rawURL)
+		if err != nil {
+			return nil, fmt.Errorf("invalid url for %s: %w", platform, err)
+		}
+		binaries[platform] = ref
+	}
+
+	return binaries, nil
+}
+
+func parseBinaryRef(rawURL string) (BinaryRef, error) {
+	u, err := neturl.Parse(rawURL)
+	if err != nil {
+		return BinaryRef{}, err
+	}
+
+	// only the checksum pairs are removed, the rest of the query is kept as is, as re-encoding
+	// it would reorder and re-escape it, breaking e.g. signed URLs
+	var (
+		checksum string
+		found    bool
+		kept     []string
+	)
+	for _, pair := range strings.Split(u.RawQuery, "&") {
+		key, value, _ := strings.Cut(pair, "=")
+		if key, err := neturl.QueryUnescape(key); err != nil || key != "checksum" {
+			kept = append(kept, pair)
+			continue
+		}
+
+		if !found {
+			if checksum, err = neturl.QueryUnescape(value); err != nil {
+				return BinaryRef{}, fmt.Errorf("invalid checksum: %w", err)
+			}
+			found = true
+		}
+	}
+	u.RawQuery = strings.Join(kept, "&")
+
+	return BinaryRef{URL: u.String(), Checksum: checksum}, nil
+}
+
 func GetBinaryURL(binaries plan.BinaryDownloadURLMap) (string, error) {
-	url, ok := binaries[OSArch()]
-	if !ok {
-		url, ok = binaries["any"]
+	return GetPreferredBinaryURL(binaries, nil)
+}
+
+// GetPreferredBinaryURL returns the URL of the first platform of the preference order listed in
+// binaries. An empty preference defaults to the runtime platform, then "any".
+func GetPreferredBinaryURL(binaries plan.BinaryDownloadURLMap, preference []string) (string, error) {
+	preference = PlatformOrder(preference)
+	for _, platform := range preference {
+		if url, ok := binaries[platform]; ok {
+			return url, nil
+		}
+	}
+
+	return "", fmt.Errorf("cannot find binary for os/arch: none of %s", strings.Join(preference, ", "))
+}
+
+// PlatformOrder returns the platforms binaries are selected for in order of preference.
+// An empty preference defaults to the runtime platform, then "any".
+func PlatformOrder(preference []string) []string {
+	if len(preference) == 0 {
+		return []string{OSArch(), "any"}
 	}
-	if !ok {
-		return "", fmt.Errorf("cannot find binary for os/arch: neither %s, nor any", OSArch())
+
+	return preference
+}
+
+// parsePlatformPreference parses a comma separated list of os/arch platforms (or "any").
+func parsePlatformPreference(input string) ([]string, error) {
+	var platforms []string
+	for _, platform := range strings.Split(input, ",") {
+		platform = strings.TrimSpace(platform)
+		if platform == "" {
+			continue
+		}
+
+		if goos, goarch, ok := strings.Cut(platform, "/"); platform != "any" && (!ok || goos == "" || goarch == "") {
+			return nil, fmt.Errorf("invalid platform %q, expected os/arch or any", platform)
+		}
+		platforms = append(platforms, platform)
 	}
 
-	return url, nil
+	return platforms, nil
 }
 
 func OSArch() string {
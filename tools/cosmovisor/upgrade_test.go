@@ -15,6 +15,7 @@ import (
 	"github.com/stretchr/testify/suite"
 
 	"cosmossdk.io/log"
+	"cosmossdk.io/x/upgrade/plan"
 	upgradetypes "cosmossdk.io/x/upgrade/types"
 	"github.com/upnodedev/cosmos-sdk/tools/cosmovisor"
 )
@@ -74,6 +75,11 @@ func (s *upgradeTestSuite) TestCurrentAlwaysSymlinkToDirectory() {
 	s.Require().NoError(err)
 	s.Require().Equal(cfg.UpgradeBin("chain2"), currentBin)
 	s.assertCurrentLink(cfg, filepath.Join("upgrades", "chain2"))
+
+	// the applied upgrade is recorded for the restart heuristic
+	bz, err := os.ReadFile(cfg.CurrentUpgradeFilePath())
+	s.Require().NoError(err)
+	s.Require().Contains(string(bz), `"name":"chain2"`)
 }
 
 func (s *upgradeTestSuite) assertCurrentLink(cfg cosmovisor.Config, target string) {
@@ -197,6 +203,116 @@ func (s *upgradeTestSuite) TestUpgradeBinary() {
 	}
 }
 
+func (s *upgradeTestSuite) TestParseUpgradeBinaries() {
+	cases := map[string]struct {
+		info      string
+		expect    map[string]cosmovisor.BinaryRef
+		expectErr bool
+	}{
+		"platforms with checksums": {
+			info: `{"binaries":{
+				"linux/amd64":"https://example.com/gaiad-v12.0.0-linux-amd64?checksum=sha256:aa11",
+				"darwin/arm64":"https://example.com/gaiad-v12.0.0-darwin-arm64.zip?checksum=sha256:bb22"
+			}}`,
+			expect: map[string]cosmovisor.BinaryRef{
+				"linux/amd64":  {URL: "https://example.com/gaiad-v12.0.0-linux-amd64", Checksum: "sha256:aa11"},
+				"darwin/arm64": {URL: "https://example.com/gaiad-v12.0.0-darwin-arm64.zip", Checksum: "sha256:bb22"},
+			},
+		},
+		"any platform without checksum": {
+			info: `{"binaries":{"any":"https://example.com/gaiad.tar.gz?ref=main"}}`,
+			expect: map[string]cosmovisor.BinaryRef{
+				"any": {URL: "https://example.com/gaiad.tar.gz?ref=main"},
+			},
+		},
+		"signed url": {
+			info: `{"binaries":{"any":"https://bucket.example.com/gaiad.tar.gz?X-Amz-Signature=ab%2Fcd&checksum=sha256:cc33&X-Amz-Date=20240101T000000Z&X-Amz-Algorithm=AWS4-HMAC-SHA256&X-Amz-Credential=AKIA%2F20240101%2Fus-east-1"}}`,
+			expect: map[string]cosmovisor.BinaryRef{
+				"any": {
+					URL:      "https://bucket.example.com/gaiad.tar.gz?X-Amz-Signature=ab%2Fcd&X-Amz-Date=20240101T000000Z&X-Amz-Algorithm=AWS4-HMAC-SHA256&X-Amz-Credential=AKIA%2F20240101%2Fus-east-1",
+					Checksum: "sha256:cc33",
+				},
+			},
+		},
+		"no binaries": {
+			info:   `{"binaries":{}}`,
+			expect: map[string]cosmovisor.BinaryRef{},
+		},
+		"invalid info": {
+			info:      `{"binaries": "not a map"}`,
+			expectErr: true,
+		},
+		"blank info": {
+			info:      "",
+			expectErr: true,
+		},
+	}
+
+	for name, tc := range cases {
+		s.Run(name, func() {
+			binaries, err := cosmovisor.ParseUpgradeBinaries(tc.info)
+			if tc.expectErr {
+				s.Require().Error(err)
+				return
+			}
+
+			s.Require().NoError(err)
+			s.Require().Equal(tc.expect, binaries)
+		})
+	}
+}
+
+func (s *upgradeTestSuite) TestGetPreferredBinaryURL() {
+	binaries := plan.BinaryDownloadURLMap{
+		"linux/amd64": "https://example.com/amd64",
+		"linux/arm64": "https://example.com/arm64",
+		"any":         "https://example.com/any",
+	}
+
+	cases := map[string]struct {
+		binaries   plan.BinaryDownloadURLMap
+		preference []string
+		expect     string
+		expectErr  bool
+	}{
+		"default prefers the runtime platform": {
+			binaries: binaries,
+			expect:   "https://example.com/amd64",
+		},
+		"default falls back to any": {
+			binaries: plan.BinaryDownloadURLMap{"linux/arm64": "https://example.com/arm64", "any": "https://example.com/any"},
+			expect:   "https://example.com/any",
+		},
+		"preference order": {
+			binaries:   binaries,
+			preference: []string{"linux/arm64", "linux/amd64", "any"},
+			expect:     "https://example.com/arm64",
+		},
+		"preference falls through": {
+			binaries:   binaries,
+			preference: []string{"darwin/arm64", "any", "linux/amd64"},
+			expect:     "https://example.com/any",
+		},
+		"no preferred platform": {
+			binaries:   binaries,
+			preference: []string{"darwin/arm64"},
+			expectErr:  true,
+		},
+	}
+
+	for name, tc := range cases {
+		s.Run(name, func() {
+			url, err := cosmovisor.GetPreferredBinaryURL(tc.binaries, tc.preference)
+			if tc.expectErr {
+				s.Require().Error(err)
+				return
+			}
+			s.Require().NoError(err)
+			s.Require().Equal(tc.expect, url)
+		})
+	}
+}
+
 func (s *upgradeTestSuite) TestOsArch() {
 	// all download tests will fail if we are not on linux...
 	s.Require().Equal("linux/amd64", cosmovisor.OSArch())
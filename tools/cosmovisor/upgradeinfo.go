@@ -0,0 +1,242 @@
+package cosmovisor
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/santhosh-tekuri/jsonschema/v5"
+
+	upgradetypes "cosmossdk.io/x/upgrade/types"
+)
+
+// utf8BOM is the byte order mark some editors prepend to UTF-8 files.
+var utf8BOM = []byte{0xEF, 0xBB, 0xBF}
+
+// ParseConfig is used to configure the parsing of an upgrade-info.json file.
+type ParseConfig struct {
+	// DisableRecase, if true, keeps the upgrade name as is. Otherwise it is lowercased.
+	DisableRecase bool
+	// RecaseExceptions are names, matched case-insensitively, kept as is even when recasing.
+	RecaseExceptions []string
+	// MaxSize, if positive, is the maximum size in bytes of the file.
+	MaxSize int64
+	// StripBOM, if true, removes a leading UTF-8 byte order mark before parsing.
+	StripBOM bool
+	// Schema, if set, is the JSON schema the file must match.
+	Schema *jsonschema.Schema
+	// ExpandInfo, if set, looks up the variables ${...} placeholders in the plan info are expanded to.
+	// Other plan fields are never expanded.
+	ExpandInfo func(name string) (string, bool)
+	// StrictJSON, if true, rejects the fields the upgrade plan does not define instead of ignoring them.
+	StrictJSON bool
+}
+
+// ParseOption is used to configure the parsing of an upgrade-info.json file.
+type ParseOption func(*ParseConfig)
+
+// ParseOptionDisableRecase returns a ParseOption that sets the DisableRecase field of the ParseConfig.
+func ParseOptionDisableRecase(disable bool) ParseOption {
+	return func(c *ParseConfig) {
+		c.DisableRecase = disable
+	}
+}
+
+// ParseOptionRecaseExceptions returns a ParseOption that sets the RecaseExceptions field of the ParseConfig.
+func ParseOptionRecaseExceptions(names []string) ParseOption {
+	return func(c *ParseConfig) {
+		c.RecaseExceptions = names
+	}
+}
+
+// ParseOptionMaxSize returns a ParseOption that sets the MaxSize field of the ParseConfig.
+func ParseOptionMaxSize(maxSize int64) ParseOption {
+	return func(c *ParseConfig) {
+		c.MaxSize = maxSize
+	}
+}
+
+// ParseOptionStripBOM returns a ParseOption that sets the StripBOM field of the ParseConfig.
+func ParseOptionStripBOM(strip bool) ParseOption {
+	return func(c *ParseConfig) {
+		c.StripBOM = strip
+	}
+}
+
+// ParseOptionSchema returns a ParseOption that sets the Schema field of the ParseConfig.
+func ParseOptionSchema(schema *jsonschema.Schema) ParseOption {
+	return func(c *ParseConfig) {
+		c.Schema = schema
+	}
+}
+
+// ParseOptionExpandInfo returns a ParseOption that sets the ExpandInfo field of the ParseConfig.
+func ParseOptionExpandInfo(lookup func(name string) (string, bool)) ParseOption {
+	return func(c *ParseConfig) {
+		c.ExpandInfo = lookup
+	}
+}
+
+// ParseOptionStrictJSON returns a ParseOption that sets the StrictJSON field of the ParseConfig.
+func ParseOptionStrictJSON(strict bool) ParseOption {
+	return func(c *ParseConfig) {
+		c.StrictJSON = strict
+	}
+}
+
+// ParseUpgradeInfoFile reads and validates the upgrade plan written to an upgrade-info.json file,
+// as cosmovisor does when watching for upgrades.
+// By default the upgrade name is lowercased, unknown fields are ignored, and neither size limit,
+// BOM stripping nor schema validation is applied.
+func ParseUpgradeInfoFile(path string, opts ...ParseOption) (upgradetypes.Plan, error) {
+	parseConfig := &ParseConfig{}
+	for _, opt := range opts {
+		opt(parseConfig)
+	}
+
+	f, err := readUpgradeInfoFile(path, parseConfig.MaxSize)
+	if err != nil {
+		return upgradetypes.Plan{}, err
+	}
+
+	if parseConfig.StripBOM {
+		f = bytes.TrimPrefix(f, utf8BOM)
+	}
+
+	if len(f) == 0 {
+		return upgradetypes.Plan{}, ErrUpgradeInfoEmpty
+	}
+
+	// the schema reports structural problems more precisely than unmarshaling and ValidateBasic
+	if parseConfig.Schema != nil {
+		if err := validateUpgradeInfoSchema(parseConfig.Schema, f); err != nil {
+			return upgradetypes.Plan{}, err
+		}
+	}
+
+	upgradePlan, err := unmarshalUpgradePlan(f, parseConfig.StrictJSON)
+	if err != nil {
+		return upgradetypes.Plan{}, err
+	}
+
+	if parseConfig.ExpandInfo != nil {
+		if upgradePlan.Info, err = expandInfo(upgradePlan.Info, parseConfig.ExpandInfo); err != nil {
+			return upgradetypes.Plan{}, err
+		}
+	}
+
+	// required values must be set
+	if err := upgradePlan.ValidateBasic(); err != nil {
+		return upgradetypes.Plan{}, fmt.Errorf("%w: %w, got: %v", ErrUpgradeInfoInvalid, err, upgradePlan)
+	}
+
+	// normalize name to prevent operator error in upgrade name case sensitivity errors.
+	if !parseConfig.DisableRecase {
+		upgradePlan.Name = RecaseUpgradeName(upgradePlan.Name, parseConfig.RecaseExceptions)
+	}
+
+	return upgradePlan, nil
+}
+
+// unmarshalUpgradePlan decodes the upgrade plan. In strict mode a field the plan does not define
+// fails with ErrUpgradeInfoUnknownField naming it, e.g. a misspelled "heigth".
+func unmarshalUpgradePlan(f []byte, strict bool) (upgradetypes.Plan, error) {
+	var upgradePlan upgradetypes.Plan
+	if !strict {
+		return upgradePlan, json.Unmarshal(f, &upgradePlan)
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(f))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&upgradePlan); err != nil {
+		// encoding/json has no typed error for unknown fields
+		if field, ok := strings.CutPrefix(err.Error(), "json: unknown field "); ok {
+			return upgradetypes.Plan{}, fmt.Errorf("%w: %s", ErrUpgradeInfoUnknownField, field)
+		}
+		return upgradetypes.Plan{}, err
+	}
+	// like json.Unmarshal, reject anything following the plan
+	if _, err := dec.Token(); !errors.Is(err, io.EOF) {
+		return upgradetypes.Plan{}, errors.New("invalid character after top-level value")
+	}
+
+	return upgradePlan, nil
+}
+
+// RecaseUpgradeName lowercases the upgrade name, unless it matches one of the exceptions
+// case-insensitively, in which case it is returned as is.
+func RecaseUpgradeName(name string, exceptions []string) string {
+	for _, exception := range exceptions {
+		if strings.EqualFold(name, exception) {
+			return name
+		}
+	}
+
+	return strings.ToLower(name)
+}
+
+// infoPlaceholder matches the ${NAME} placeholders of the plan info.
+var infoPlaceholder = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+// expandInfo expands the ${NAME} placeholders of the plan info, failing if any of them is
+// undefined. Anything else, e.g. a bare $NAME, is left as is.
+func expandInfo(info string, lookup func(name string) (string, bool)) (string, error) {
+	var missing []string
+	expanded := infoPlaceholder.ReplaceAllStringFunc(info, func(placeholder string) string {
+		name := infoPlaceholder.FindStringSubmatch(placeholder)[1]
+		val, ok := lookup(name)
+		if !ok {
+			missing = append(missing, name)
+		}
+		return val
+	})
+	if len(missing) > 0 {
+		return "", fmt.Errorf("%w: undefined variables in info: %s", ErrUpgradeInfoInvalid, strings.Join(missing, ", "))
+	}
+
+	return expanded, nil
+}
+
+// LookupInfoEnv looks up the variables of the plan info in the env vars prefixed with
+// COSMOVISOR_INFO_, e.g. ${BASE_URL} is expanded to $COSMOVISOR_INFO_BASE_URL.
+func LookupInfoEnv(name string) (string, bool) {
+	return os.LookupEnv(EnvInfoVarPrefix + name)
+}
+
+// unreadableError wraps a permission error reading the upgrade-info.json file in ErrUpgradeInfoUnreadable.
+func unreadableError(err error) error {
+	if errors.Is(err, fs.ErrPermission) {
+		return fmt.Errorf("%w: %w", ErrUpgradeInfoUnreadable, err)
+	}
+	return err
+}
+
+// readUpgradeInfoFile reads the file, failing if it is larger than maxSize when positive.
+func readUpgradeInfoFile(path string, maxSize int64) ([]byte, error) {
+	if maxSize <= 0 {
+		f, err := os.ReadFile(path)
+		return f, unreadableError(err)
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, unreadableError(err)
+	}
+	defer file.Close()
+
+	f, err := io.ReadAll(io.LimitReader(file, maxSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(f)) > maxSize {
+		return nil, fmt.Errorf("%w: exceeds %d bytes", ErrUpgradeInfoTooLarge, maxSize)
+	}
+
+	return f, nil
+}
@@ -0,0 +1,188 @@
+package cosmovisor
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	upgradetypes "cosmossdk.io/x/upgrade/types"
+)
+
+func TestParseUpgradeInfoFileOptions(t *testing.T) {
+	content := `{"name":"Upgrade1","height":123}`
+	bom := string(utf8BOM)
+
+	cases := map[string]struct {
+		content   string
+		opts      []ParseOption
+		expect    upgradetypes.Plan
+		expectErr error
+	}{
+		"defaults": {
+			content: content,
+			expect:  upgradetypes.Plan{Name: "upgrade1", Height: 123},
+		},
+		"disable recase": {
+			content: content,
+			opts:    []ParseOption{ParseOptionDisableRecase(true)},
+			expect:  upgradetypes.Plan{Name: "Upgrade1", Height: 123},
+		},
+		"recase exception": {
+			content: content,
+			opts:    []ParseOption{ParseOptionRecaseExceptions([]string{"upgrade1"})},
+			expect:  upgradetypes.Plan{Name: "Upgrade1", Height: 123},
+		},
+		"not a recase exception": {
+			content: content,
+			opts:    []ParseOption{ParseOptionRecaseExceptions([]string{"Upgrade2"})},
+			expect:  upgradetypes.Plan{Name: "upgrade1", Height: 123},
+		},
+		"within size limit": {
+			content: content,
+			opts:    []ParseOption{ParseOptionMaxSize(int64(len(content)))},
+			expect:  upgradetypes.Plan{Name: "upgrade1", Height: 123},
+		},
+		"exceeds size limit": {
+			content:   content,
+			opts:      []ParseOption{ParseOptionMaxSize(int64(len(content)) - 1)},
+			expectErr: ErrUpgradeInfoTooLarge,
+		},
+		"strip bom": {
+			content: bom + content,
+			opts:    []ParseOption{ParseOptionStripBOM(true)},
+			expect:  upgradetypes.Plan{Name: "upgrade1", Height: 123},
+		},
+		"only bom": {
+			content:   bom,
+			opts:      []ParseOption{ParseOptionStripBOM(true)},
+			expectErr: ErrUpgradeInfoEmpty,
+		},
+		"unknown field ignored": {
+			content: `{"name":"upgrade1","heigth":123,"height":123}`,
+			expect:  upgradetypes.Plan{Name: "upgrade1", Height: 123},
+		},
+		"unknown field strict": {
+			content:   `{"name":"upgrade1","heigth":123,"height":123}`,
+			opts:      []ParseOption{ParseOptionStrictJSON(true)},
+			expectErr: ErrUpgradeInfoUnknownField,
+		},
+		"known fields strict": {
+			content: content,
+			opts:    []ParseOption{ParseOptionStrictJSON(true)},
+			expect:  upgradetypes.Plan{Name: "upgrade1", Height: 123},
+		},
+		"invalid plan": {
+			content:   `{"name":"upgrade1"}`,
+			expectErr: ErrUpgradeInfoInvalid,
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "upgrade-info.json")
+			require.NoError(t, os.WriteFile(path, []byte(tc.content), 0o600))
+
+			plan, err := ParseUpgradeInfoFile(path, tc.opts...)
+			if tc.expectErr != nil {
+				require.ErrorIs(t, err, tc.expectErr)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.expect, plan)
+		})
+	}
+}
+
+func TestParseUpgradeInfoFileBOMNotStripped(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "upgrade-info.json")
+	require.NoError(t, os.WriteFile(path, append(utf8BOM, `{"name":"upgrade1","height":123}`...), 0o600))
+
+	_, err := ParseUpgradeInfoFile(path)
+	require.Error(t, err)
+}
+
+func TestParseUpgradeInfoFileStrictJSONNamesField(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "upgrade-info.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{"name":"upgrade1","heigth":123}`), 0o600))
+
+	_, err := ParseUpgradeInfoFile(path, ParseOptionStrictJSON(true))
+	require.ErrorIs(t, err, ErrUpgradeInfoUnknownField)
+	require.ErrorContains(t, err, `"heigth"`)
+}
+
+func TestParseUpgradeInfoFileExpandInfo(t *testing.T) {
+	vars := map[string]string{"BASE_URL": "https://mirror.example.com", "VERSION": "v2.0.0"}
+	lookup := func(name string) (string, bool) {
+		val, ok := vars[name]
+		return val, ok
+	}
+
+	cases := map[string]struct {
+		content   string
+		opts      []ParseOption
+		expect    upgradetypes.Plan
+		expectErr string
+	}{
+		"expanded": {
+			content: `{"name":"${VERSION}","height":123,"info":"{\"binaries\":{\"any\":\"${BASE_URL}/${VERSION}/simd\"}}"}`,
+			opts:    []ParseOption{ParseOptionExpandInfo(lookup)},
+			expect:  upgradetypes.Plan{Name: "${version}", Height: 123, Info: `{"binaries":{"any":"https://mirror.example.com/v2.0.0/simd"}}`},
+		},
+		"placeholders only": {
+			content: `{"name":"upgrade1","height":123,"info":"$BASE_URL/${VERSION}/$$/${}/$(VERSION)"}`,
+			opts:    []ParseOption{ParseOptionExpandInfo(lookup)},
+			expect:  upgradetypes.Plan{Name: "upgrade1", Height: 123, Info: "$BASE_URL/v2.0.0/$$/${}/$(VERSION)"},
+		},
+		"disabled by default": {
+			content: `{"name":"upgrade1","height":123,"info":"${BASE_URL}/simd"}`,
+			expect:  upgradetypes.Plan{Name: "upgrade1", Height: 123, Info: "${BASE_URL}/simd"},
+		},
+		"missing variables": {
+			content:   `{"name":"upgrade1","height":123,"info":"${BASE_URL}/${CHAIN}/${ARCH}"}`,
+			opts:      []ParseOption{ParseOptionExpandInfo(lookup)},
+			expectErr: "undefined variables in info: CHAIN, ARCH",
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "upgrade-info.json")
+			require.NoError(t, os.WriteFile(path, []byte(tc.content), 0o600))
+
+			plan, err := ParseUpgradeInfoFile(path, tc.opts...)
+			if tc.expectErr != "" {
+				require.ErrorIs(t, err, ErrUpgradeInfoInvalid)
+				require.ErrorContains(t, err, tc.expectErr)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.expect, plan)
+		})
+	}
+}
+
+func TestLookupInfoEnv(t *testing.T) {
+	t.Setenv("COSMOVISOR_INFO_BASE_URL", "https://mirror.example.com")
+	t.Setenv("BASE_URL", "https://uncontrolled.example.com")
+
+	cfg := &Config{ExpandInfoEnv: true}
+	path := filepath.Join(t.TempDir(), "upgrade-info.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{"name":"upgrade1","height":123,"info":"${BASE_URL}/simd"}`), 0o600))
+
+	plan, err := ParseUpgradeInfoFile(path, cfg.UpgradeInfoParseOptions()...)
+	require.NoError(t, err)
+	require.Equal(t, "https://mirror.example.com/simd", plan.Info)
+}
+
+func TestRecaseUpgradeName(t *testing.T) {
+	exceptions := []string{"v2-MainNet", "IBCUpgrade"}
+
+	require.Equal(t, "v3-upgrade", RecaseUpgradeName("V3-Upgrade", exceptions))
+	require.Equal(t, "v2-MainNet", RecaseUpgradeName("v2-MainNet", exceptions))
+	// exceptions match case-insensitively and the incoming name is left untouched
+	require.Equal(t, "IbcUpgrade", RecaseUpgradeName("IbcUpgrade", exceptions))
+	require.Equal(t, "ibcupgrade2", RecaseUpgradeName("IBCUpgrade2", exceptions))
+	require.Equal(t, "v2-mainnet", RecaseUpgradeName("v2-MainNet", nil))
+}
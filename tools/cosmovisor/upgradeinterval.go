@@ -0,0 +1,40 @@
+package cosmovisor
+
+import (
+	"fmt"
+	"time"
+
+	upgradetypes "cosmossdk.io/x/upgrade/types"
+)
+
+// checkUpgradeInterval reports whether the upgrade to info may be signaled, that is unless another
+// upgrade was signaled less than minUpgradeInterval ago, whether before a restart or not. Signaling
+// the same upgrade again is not deferred. A deferred upgrade is held and the interval checked again
+// on every poll, an upgrade_held callback being emitted once when the hold starts.
+func (fw *fileWatcher) checkUpgradeInterval(info upgradetypes.Plan, callback callbackInfo) bool {
+	last := fw.state.LastSignal
+	if fw.minUpgradeInterval <= 0 || last == nil || last.Name == info.Name && last.Height == info.Height {
+		fw.intervalHeld = false
+		return true
+	}
+
+	elapsed := fw.now().Sub(fw.state.LastSignalTime)
+	if elapsed >= fw.minUpgradeInterval {
+		if fw.intervalHeld {
+			fw.logger.Info("minimum upgrade interval elapsed, proceeding with the held upgrade", "name", info.Name)
+		}
+		fw.intervalHeld = false
+		return true
+	}
+
+	reason := fmt.Sprintf("upgrade %s signaled %s ago, less than the minimum upgrade interval of %s", last.Name, elapsed.Round(time.Second), fw.minUpgradeInterval)
+	fw.logger.Error("upgrade held", "name", info.Name, "height", info.Height, "reason", reason)
+	if !fw.intervalHeld {
+		fw.intervalHeld = true
+		fw.heldCallback = callback
+		callback.Error = reason
+		_ = fw.callbacks.send(CallbackEventUpgradeHeld, callback)
+	}
+
+	return false
+}
@@ -0,0 +1,143 @@
+package cosmovisor
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	upgradetypes "cosmossdk.io/x/upgrade/types"
+)
+
+func TestCheckUpdateMinUpgradeInterval(t *testing.T) {
+	require := require.New(t)
+
+	srv := newCallbackRecorder(t)
+	cfg := &Config{Home: t.TempDir(), Name: "dummyd", CallbackAPI: srv.URL, MinUpgradeInterval: time.Hour}
+	held := "/internal/cosmos///" + callbackPaths[CallbackEventUpgradeHeld]
+	start := time.Date(2023, 7, 1, 12, 0, 0, 0, time.UTC)
+	now := start
+	var height int64 = 49
+
+	// the interval is tracked across restarts
+	restart := func() *fileWatcher {
+		fw := newTestWatcher(t, cfg)
+		fw.now = func() time.Time { return now }
+		fw.getHeight = func() (int64, error) { return height, nil }
+		return fw
+	}
+
+	fw := restart()
+	writeUpgradeInfo(t, cfg, upgradetypes.Plan{Name: "chain2", Height: 49})
+	require.True(fw.CheckUpdate(upgradetypes.Plan{}))
+	state, err := loadWatcherState(cfg.StateFilePath())
+	require.NoError(err)
+	require.True(start.Equal(state.LastSignalTime))
+
+	// the next upgrade comes too soon after the previous one and is deferred
+	now = start.Add(10 * time.Minute)
+	height = 60
+	fw = restart()
+	writeUpgradeInfo(t, cfg, upgradetypes.Plan{Name: "chain3", Height: 60})
+	require.False(fw.CheckUpdate(upgradetypes.Plan{Name: "chain2"}))
+	require.False(fw.CheckUpdate(upgradetypes.Plan{Name: "chain2"}))
+	require.False(fw.needsUpdate)
+	got := srv.received(held)
+	require.Len(got, 1)
+	require.Equal("chain3", got[0].Name)
+	require.Contains(got[0].Error, "upgrade chain2 signaled 10m0s ago, less than the minimum upgrade interval of 1h0m0s")
+
+	// it proceeds once the interval elapsed
+	now = start.Add(time.Hour)
+	require.True(fw.CheckUpdate(upgradetypes.Plan{Name: "chain2"}))
+	require.Len(srv.received(held), 1)
+	state, err = loadWatcherState(cfg.StateFilePath())
+	require.NoError(err)
+	require.Equal(&upgradeSignal{Name: "chain3", Height: 60, Count: 1}, state.LastSignal)
+	require.True(now.Equal(state.LastSignalTime))
+}
+
+func TestCheckUpdateMinUpgradeIntervalSameUpgrade(t *testing.T) {
+	cfg := &Config{Home: t.TempDir(), Name: "dummyd", MinUpgradeInterval: time.Hour}
+	restart := func() *fileWatcher {
+		fw := newTestWatcher(t, cfg)
+		fw.getHeight = func() (int64, error) { return 49, nil }
+		return fw
+	}
+
+	// signaling the same upgrade again, e.g. after its binary crashed, is not deferred
+	fw := restart()
+	writeUpgradeInfo(t, cfg, upgradetypes.Plan{Name: "chain2", Height: 49})
+	require.True(t, fw.CheckUpdate(upgradetypes.Plan{}))
+	require.True(t, restart().CheckUpdate(upgradetypes.Plan{}))
+}
+
+func TestCheckUpdateMinUpgradeIntervalNewTrigger(t *testing.T) {
+	require := require.New(t)
+
+	srv := newCallbackRecorder(t)
+	cfg := &Config{Home: t.TempDir(), Name: "dummyd", CallbackAPI: srv.URL, MinUpgradeInterval: time.Hour}
+	held := "/internal/cosmos///" + callbackPaths[CallbackEventUpgradeHeld]
+	detected := "/internal/cosmos///" + callbackPaths[CallbackEventDetected]
+	start := time.Date(2023, 7, 1, 12, 0, 0, 0, time.UTC)
+	now := start
+	fw := newTestWatcher(t, cfg)
+	fw.now = func() time.Time { return now }
+	fw.getHeight = func() (int64, error) { return 60, nil }
+	fw.state.LastSignal = &upgradeSignal{Name: "chain2", Height: 49, Count: 1}
+	fw.state.LastSignalTime = start
+
+	// rewrite bumps the modification time so the file is read again
+	modTime := time.Now()
+	rewrite := func(p upgradetypes.Plan) {
+		writeUpgradeInfo(t, cfg, p)
+		modTime = modTime.Add(time.Second)
+		require.NoError(os.Chtimes(cfg.UpgradeInfoFilePath(), modTime, modTime))
+	}
+
+	rewrite(upgradetypes.Plan{Name: "chain3", Height: 60})
+	require.False(fw.CheckUpdate(upgradetypes.Plan{Name: "chain2"}))
+	require.True(fw.intervalHeld)
+	require.Len(srv.received(detected), 1)
+
+	// a trigger for a later upgrade is reported while the upgrade is held
+	rewrite(upgradetypes.Plan{Name: "chain4", Height: 70})
+	require.False(fw.CheckUpdate(upgradetypes.Plan{Name: "chain2"}))
+	got := srv.received(detected)
+	require.Len(got, 2)
+	require.Equal("chain4", got[1].Name)
+	require.True(fw.intervalHeld)
+
+	// an amended plan takes over the hold
+	rewrite(upgradetypes.Plan{Name: "chain3", Height: 60, Info: "amended"})
+	require.False(fw.CheckUpdate(upgradetypes.Plan{Name: "chain2"}))
+	got = srv.received(held)
+	require.Len(got, 2)
+	require.Equal("amended", got[1].Info)
+
+	now = start.Add(time.Hour)
+	require.True(fw.CheckUpdate(upgradetypes.Plan{Name: "chain2"}))
+	require.Equal("amended", fw.currentInfo.Info)
+}
+
+func TestCheckUpdateMinUpgradeIntervalAfterExit(t *testing.T) {
+	cfg := &Config{Home: t.TempDir(), Name: "dummyd", MinUpgradeInterval: time.Hour}
+	start := time.Date(2023, 7, 1, 12, 0, 0, 0, time.UTC)
+	now := start
+	fw := newTestWatcher(t, cfg)
+	fw.now = func() time.Time { return now }
+	fw.getHeight = func() (int64, error) { return 60, nil }
+	fw.state.LastSignal = &upgradeSignal{Name: "chain2", Height: 49, Count: 1}
+	fw.state.LastSignalTime = start
+	writeUpgradeInfo(t, cfg, upgradetypes.Plan{Name: "chain3", Height: 60})
+
+	// the app exits while the upgrade is held, it goes through once the interval elapsed
+	polls := 0
+	fw.sleep = func(time.Duration) {
+		polls++
+		now = now.Add(20 * time.Minute)
+	}
+	require.True(t, fw.checkAfterExit(upgradetypes.Plan{Name: "chain2"}))
+	require.Equal(t, 3, polls)
+}
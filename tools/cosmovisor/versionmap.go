@@ -0,0 +1,72 @@
+package cosmovisor
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// VersionRef is the version, and optionally the repository, an upgrade installs.
+type VersionRef struct {
+	Version string
+	Repo    string
+}
+
+// String returns the ref in the [repo@]version format it is parsed from.
+func (r VersionRef) String() string {
+	if r.Repo == "" {
+		return r.Version
+	}
+
+	return r.Repo + "@" + r.Version
+}
+
+// lookupVersionRef returns the ref of the named upgrade, matching the name case-insensitively.
+func lookupVersionRef(refs map[string]VersionRef, name string) (VersionRef, bool) {
+	if ref, ok := refs[name]; ok {
+		return ref, true
+	}
+	for key, ref := range refs {
+		if strings.EqualFold(key, name) {
+			return ref, true
+		}
+	}
+
+	return VersionRef{}, false
+}
+
+// parseNameVersionMap parses a comma separated list of name=[repo@]version pairs, the repo
+// being split at the last @.
+func parseNameVersionMap(input string) (map[string]VersionRef, error) {
+	pairs, err := parseEnvMap(input)
+	if err != nil {
+		return nil, err
+	}
+
+	refs := make(map[string]VersionRef, len(pairs))
+	for name, value := range pairs {
+		var ref VersionRef
+		if i := strings.LastIndex(value, "@"); i >= 0 {
+			ref.Repo, ref.Version = value[:i], value[i+1:]
+		} else {
+			ref.Version = value
+		}
+		if ref.Version == "" {
+			return nil, fmt.Errorf("missing version for upgrade %s", name)
+		}
+		refs[name] = ref
+	}
+
+	return refs, nil
+}
+
+// formatNameVersionMap is the inverse of parseNameVersionMap.
+func formatNameVersionMap(refs map[string]VersionRef) string {
+	pairs := make([]string, 0, len(refs))
+	for name, ref := range refs {
+		pairs = append(pairs, name+"="+ref.String())
+	}
+	sort.Strings(pairs)
+
+	return strings.Join(pairs, ",")
+}
@@ -0,0 +1,98 @@
+package cosmovisor
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	upgradetypes "cosmossdk.io/x/upgrade/types"
+)
+
+func TestCheckUpdateNameVersionMap(t *testing.T) {
+	refs := map[string]VersionRef{
+		"chain2": {Version: "v2.0.0", Repo: "https://github.com/cosmos/mirror"},
+	}
+
+	cases := map[string]struct {
+		info          string
+		expectVersion string
+		expectRepo    string
+	}{
+		"no binaries, fallback used": {
+			info:          "",
+			expectVersion: "v2.0.0",
+			expectRepo:    "https://github.com/cosmos/mirror",
+		},
+		"unversioned url, fallback used": {
+			info:          `{"binaries":{"any":"https://mirror.local/gaiad"}}`,
+			expectVersion: "v2.0.0",
+			expectRepo:    "https://github.com/cosmos/mirror",
+		},
+		"url extraction succeeds": {
+			info:          `{"binaries":{"any":"https://github.com/cosmos/gaia/releases/download/v2.1.0/gaiad"}}`,
+			expectVersion: "v2.1.0",
+			expectRepo:    "https://github.com/cosmos/gaia",
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			srv := newCallbackRecorder(t)
+			cfg := &Config{Home: t.TempDir(), Name: "dummyd", CallbackAPI: srv.URL, NameVersionMap: refs}
+			fw := newTestWatcher(t, cfg)
+			fw.getHeight = func() (int64, error) { return 10, nil }
+
+			writeUpgradeInfo(t, cfg, upgradetypes.Plan{Name: "Chain2", Height: 49, Info: tc.info})
+			require.False(t, fw.CheckUpdate(upgradetypes.Plan{}))
+			got := srv.received("/internal/cosmos///" + callbackPaths[CallbackEventDetected])
+			require.Len(t, got, 1)
+			require.Equal(t, tc.expectVersion, got[0].Version)
+			require.Equal(t, tc.expectRepo, got[0].Repo)
+		})
+	}
+}
+
+func TestCheckUpdateNameVersionMapUnknown(t *testing.T) {
+	srv := newCallbackRecorder(t)
+	cfg := &Config{Home: t.TempDir(), Name: "dummyd", CallbackAPI: srv.URL, NameVersionMap: map[string]VersionRef{"chain3": {Version: "v3.0.0"}}}
+	fw := newTestWatcher(t, cfg)
+	fw.getHeight = func() (int64, error) { return 10, nil }
+
+	writeUpgradeInfo(t, cfg, upgradetypes.Plan{Name: "chain2", Height: 49})
+	require.False(t, fw.CheckUpdate(upgradetypes.Plan{}))
+	got := srv.received("/internal/cosmos///" + callbackPaths[CallbackEventDetected])
+	require.Len(t, got, 1)
+	require.Empty(t, got[0].Version)
+	require.Empty(t, got[0].Repo)
+}
+
+func TestParseNameVersionMap(t *testing.T) {
+	cases := map[string]struct {
+		input     string
+		expect    map[string]VersionRef
+		expectErr bool
+	}{
+		"version only":   {input: "v2=v2.0.0", expect: map[string]VersionRef{"v2": {Version: "v2.0.0"}}},
+		"repo@version":   {input: "v2 = https://github.com/cosmos/gaia@v2.0.0", expect: map[string]VersionRef{"v2": {Version: "v2.0.0", Repo: "https://github.com/cosmos/gaia"}}},
+		"split at last":  {input: "v2=https://user@host/gaia@v2.0.0", expect: map[string]VersionRef{"v2": {Version: "v2.0.0", Repo: "https://user@host/gaia"}}},
+		"several":        {input: "v2=v2.0.0,,v3=gaia@v3.0.0", expect: map[string]VersionRef{"v2": {Version: "v2.0.0"}, "v3": {Version: "v3.0.0", Repo: "gaia"}}},
+		"missing value":  {input: "v2=", expectErr: true},
+		"missing at ver": {input: "v2=gaia@", expectErr: true},
+		"not a pair":     {input: "v2.0.0", expectErr: true},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			refs, err := parseNameVersionMap(tc.input)
+			if tc.expectErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.expect, refs)
+		})
+	}
+
+	refs := map[string]VersionRef{"v3": {Version: "v3.0.0", Repo: "gaia"}, "v2": {Version: "v2.0.0"}}
+	require.Equal(t, "v2=v2.0.0,v3=gaia@v3.0.0", formatNameVersionMap(refs))
+}